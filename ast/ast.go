@@ -10,6 +10,8 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // position of the first character of the node
+	End() token.Position // position just past the last character of the node
 }
 
 //Statement is ...
@@ -44,11 +46,28 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+//Pos is of Program
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
+//End is of Program
+func (p *Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return token.Position{}
+}
+
 //LetStatement is ...
 type LetStatement struct {
-	Token token.Token // the token.LET token
-	Name  *Identifier
-	Value Expression
+	Token   token.Token // the token.LET token
+	Name    *Identifier
+	Pattern Pattern // set instead of Name by let [a, b] = ... or let {a} = ...
+	Value   Expression
 }
 
 func (ls *LetStatement) statementNode() {}
@@ -58,10 +77,31 @@ func (ls *LetStatement) TokenLiteral() string {
 	return ls.Token.Literal
 }
 
+//Pos is of LetStatement
+func (ls *LetStatement) Pos() token.Position { return ls.Token.Pos }
+
+//End is of LetStatement
+func (ls *LetStatement) End() token.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+	if ls.Pattern != nil {
+		return ls.Pattern.End()
+	}
+	if ls.Name != nil {
+		return ls.Name.End()
+	}
+	return ls.Token.End
+}
+
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.String())
+	}
 	out.WriteString(" = ")
 	if ls.Value != nil {
 		out.WriteString(ls.Value.String())
@@ -85,6 +125,12 @@ func (i *Identifier) TokenLiteral() string {
 
 func (i *Identifier) String() string { return i.Value }
 
+//Pos is of Identifier
+func (i *Identifier) Pos() token.Position { return i.Token.Pos }
+
+//End is of Identifier
+func (i *Identifier) End() token.Position { return i.Token.End }
+
 //ReturnStatement is ...
 type ReturnStatement struct {
 	Token       token.Token // the 'return' token
@@ -96,6 +142,17 @@ func (rs *ReturnStatement) statementNode() {}
 //TokenLiteral is ...
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
 
+//Pos is of ReturnStatement
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Pos }
+
+//End is of ReturnStatement
+func (rs *ReturnStatement) End() token.Position {
+	if rs.ReturnValue != nil {
+		return rs.ReturnValue.End()
+	}
+	return rs.Token.End
+}
+
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(rs.TokenLiteral() + " ")
@@ -106,6 +163,252 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+//WhileStatement runs Body for as long as Condition is truthy
+type WhileStatement struct {
+	Token     token.Token // the 'while' token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode() {}
+
+//TokenLiteral is of WhileStatement
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+
+//Pos is of WhileStatement
+func (ws *WhileStatement) Pos() token.Position { return ws.Token.Pos }
+
+//End is of WhileStatement
+func (ws *WhileStatement) End() token.Position {
+	if ws.Body != nil {
+		return ws.Body.End()
+	}
+	return ws.Token.End
+}
+
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("while")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+	return out.String()
+}
+
+//ForInStatement runs Body once for each item of Iterable. With one name,
+//Value is bound to each character, element or [key, value] pair; with
+//two, Key is bound to the index or key and Value to the item or value.
+type ForInStatement struct {
+	Token    token.Token // the 'for' token
+	Key      *Identifier // nil in the one-name form
+	Value    *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForInStatement) statementNode() {}
+
+//TokenLiteral is of ForInStatement
+func (fs *ForInStatement) TokenLiteral() string { return fs.Token.Literal }
+
+//Pos is of ForInStatement
+func (fs *ForInStatement) Pos() token.Position { return fs.Token.Pos }
+
+//End is of ForInStatement
+func (fs *ForInStatement) End() token.Position {
+	if fs.Body != nil {
+		return fs.Body.End()
+	}
+	return fs.Token.End
+}
+
+func (fs *ForInStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("for (")
+	if fs.Key != nil {
+		out.WriteString(fs.Key.String() + ", ")
+	}
+	out.WriteString(fs.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+	return out.String()
+}
+
+//ImportStatement binds Alias to the module loaded from Path:
+//import "lib/strings.osp" as s
+type ImportStatement struct {
+	Token token.Token // the 'import' token
+	Path  *StringLiteral
+	Alias *Identifier
+}
+
+func (is *ImportStatement) statementNode() {}
+
+//TokenLiteral is of ImportStatement
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+
+//Pos is of ImportStatement
+func (is *ImportStatement) Pos() token.Position { return is.Token.Pos }
+
+//End is of ImportStatement
+func (is *ImportStatement) End() token.Position {
+	if is.Alias != nil {
+		return is.Alias.End()
+	}
+	return is.Token.End
+}
+
+func (is *ImportStatement) String() string {
+	return "import \"" + is.Path.String() + "\" as " + is.Alias.String() + ";"
+}
+
+//ExportStatement is a top-level let whose names a module exports
+type ExportStatement struct {
+	Token     token.Token // the 'export' token
+	Statement *LetStatement
+}
+
+func (es *ExportStatement) statementNode() {}
+
+//TokenLiteral is of ExportStatement
+func (es *ExportStatement) TokenLiteral() string { return es.Token.Literal }
+
+//Pos is of ExportStatement
+func (es *ExportStatement) Pos() token.Position { return es.Token.Pos }
+
+//End is of ExportStatement
+func (es *ExportStatement) End() token.Position {
+	if es.Statement != nil {
+		return es.Statement.End()
+	}
+	return es.Token.End
+}
+
+func (es *ExportStatement) String() string {
+	return "export " + es.Statement.String()
+}
+
+//StructStatement declares a struct type and binds Name to its
+//constructor: struct Point { x, y, fn norm() { ... } }
+type StructStatement struct {
+	Token   token.Token // the 'struct' token
+	Name    *Identifier
+	Fields  []*Identifier
+	Methods []*StructMethod
+	Rbrace  token.Position // position of the closing }
+}
+
+//StructMethod is a method of a StructStatement: fn name(params) { body }
+type StructMethod struct {
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+func (sm *StructMethod) String() string {
+	return "fn " + sm.Name.String() + strings.TrimPrefix(sm.Function.String(), sm.Function.TokenLiteral())
+}
+
+func (ss *StructStatement) statementNode() {}
+
+//TokenLiteral is of StructStatement
+func (ss *StructStatement) TokenLiteral() string { return ss.Token.Literal }
+
+//Pos is of StructStatement
+func (ss *StructStatement) Pos() token.Position { return ss.Token.Pos }
+
+//End is of StructStatement
+func (ss *StructStatement) End() token.Position {
+	if ss.Rbrace.IsValid() {
+		return after(ss.Rbrace)
+	}
+	return ss.Token.End
+}
+
+func (ss *StructStatement) String() string {
+	var out bytes.Buffer
+
+	members := []string{}
+	for _, field := range ss.Fields {
+		members = append(members, field.String())
+	}
+	for _, method := range ss.Methods {
+		members = append(members, method.String())
+	}
+
+	out.WriteString("struct " + ss.Name.String() + " {")
+	if len(members) > 0 {
+		out.WriteString(" " + strings.Join(members, ", ") + " ")
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+//ThrowStatement raises Value, an error or a message, as an error that
+//unwinds to the innermost try with a catch
+type ThrowStatement struct {
+	Token token.Token // the 'throw' token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode() {}
+
+//TokenLiteral is of ThrowStatement
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+
+//Pos is of ThrowStatement
+func (ts *ThrowStatement) Pos() token.Position { return ts.Token.Pos }
+
+//End is of ThrowStatement
+func (ts *ThrowStatement) End() token.Position {
+	if ts.Value != nil {
+		return ts.Value.End()
+	}
+	return ts.Token.End
+}
+
+func (ts *ThrowStatement) String() string {
+	return "throw " + ts.Value.String() + ";"
+}
+
+//BreakStatement leaves the innermost loop
+type BreakStatement struct {
+	Token token.Token // the 'break' token
+}
+
+func (bs *BreakStatement) statementNode() {}
+
+//TokenLiteral is of BreakStatement
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+
+//Pos is of BreakStatement
+func (bs *BreakStatement) Pos() token.Position { return bs.Token.Pos }
+
+//End is of BreakStatement
+func (bs *BreakStatement) End() token.Position { return bs.Token.End }
+
+func (bs *BreakStatement) String() string { return bs.TokenLiteral() + ";" }
+
+//ContinueStatement starts the next iteration of the innermost loop
+type ContinueStatement struct {
+	Token token.Token // the 'continue' token
+}
+
+func (cs *ContinueStatement) statementNode() {}
+
+//TokenLiteral is of ContinueStatement
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+
+//Pos is of ContinueStatement
+func (cs *ContinueStatement) Pos() token.Position { return cs.Token.Pos }
+
+//End is of ContinueStatement
+func (cs *ContinueStatement) End() token.Position { return cs.Token.End }
+
+func (cs *ContinueStatement) String() string { return cs.TokenLiteral() + ";" }
+
 //ExpressionStatement is ...
 type ExpressionStatement struct {
 	Token      token.Token
@@ -126,6 +429,17 @@ func (p *Program) String() string {
 	return out.String()
 }
 
+//Pos is of ExpressionStatement
+func (es *ExpressionStatement) Pos() token.Position { return es.Token.Pos }
+
+//End is of ExpressionStatement
+func (es *ExpressionStatement) End() token.Position {
+	if es.Expression != nil {
+		return es.Expression.End()
+	}
+	return es.Token.End
+}
+
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -145,6 +459,30 @@ func (il *IntegerLiteral) expressionNode() {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+//Pos is of IntegerLiteral
+func (il *IntegerLiteral) Pos() token.Position { return il.Token.Pos }
+
+//End is of IntegerLiteral
+func (il *IntegerLiteral) End() token.Position { return il.Token.End }
+
+//FloatLiteral is ...
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+
+//TokenLiteral is of FloatLiteral
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
+//Pos is of FloatLiteral
+func (fl *FloatLiteral) Pos() token.Position { return fl.Token.Pos }
+
+//End is of FloatLiteral
+func (fl *FloatLiteral) End() token.Position { return fl.Token.End }
+
 //PrefixExpression is
 type PrefixExpression struct {
 	Token    token.Token // The prefix token, e.g. !
@@ -156,6 +494,17 @@ func (pe *PrefixExpression) expressionNode() {}
 
 //TokenLiteral is of PrefixExpression
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+//Pos is of PrefixExpression
+func (pe *PrefixExpression) Pos() token.Position { return pe.Token.Pos }
+
+//End is of PrefixExpression
+func (pe *PrefixExpression) End() token.Position {
+	if pe.Right != nil {
+		return pe.Right.End()
+	}
+	return pe.Token.End
+}
+
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -165,6 +514,39 @@ func (pe *PrefixExpression) String() string {
 	return out.String()
 }
 
+//AssignExpression stores Value in Target, an identifier or an index
+//expression. Operator is "=" or a compound assignment such as "+=".
+type AssignExpression struct {
+	Token    token.Token // the assignment operator token
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+
+//Pos is of AssignExpression
+func (ae *AssignExpression) Pos() token.Position { return ae.Target.Pos() }
+
+//End is of AssignExpression
+func (ae *AssignExpression) End() token.Position {
+	if ae.Value != nil {
+		return ae.Value.End()
+	}
+	return ae.Token.End
+}
+
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+	return out.String()
+}
+
 //InfixExpression is ...
 type InfixExpression struct {
 	Token    token.Token
@@ -177,6 +559,22 @@ func (oe *InfixExpression) expressionNode() {}
 
 //TokenLiteral is of InfixOperation
 func (oe *InfixExpression) TokenLiteral() string { return oe.Token.Literal }
+//Pos is of InfixExpression
+func (oe *InfixExpression) Pos() token.Position {
+	if oe.Left != nil {
+		return oe.Left.Pos()
+	}
+	return oe.Token.Pos
+}
+
+//End is of InfixExpression
+func (oe *InfixExpression) End() token.Position {
+	if oe.Right != nil {
+		return oe.Right.End()
+	}
+	return oe.Token.End
+}
+
 func (oe *InfixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -193,6 +591,12 @@ func (b *Boolean) expressionNode() {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
+//Pos is of Boolean
+func (b *Boolean) Pos() token.Position { return b.Token.Pos }
+
+//End is of Boolean
+func (b *Boolean) End() token.Position { return b.Token.End }
+
 //IfExpression is
 type IfExpression struct {
 	Token       token.Token // The 'if' token
@@ -205,6 +609,20 @@ func (ie *IfExpression) expressionNode() {}
 
 //TokenLiteral is of IfExpression
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+//Pos is of IfExpression
+func (ie *IfExpression) Pos() token.Position { return ie.Token.Pos }
+
+//End is of IfExpression
+func (ie *IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	if ie.Consequence != nil {
+		return ie.Consequence.End()
+	}
+	return ie.Token.End
+}
+
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("if")
@@ -218,16 +636,79 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+//TryExpression evaluates Body. If that raises an error, Catch is
+//evaluated instead, with the error bound to Param if there is one.
+//Finally, if given, runs last in any case. It has a Catch, a Finally or
+//both.
+type TryExpression struct {
+	Token   token.Token // the 'try' token
+	Body    *BlockStatement
+	Param   *Identifier // the name in catch (e), or nil
+	Catch   *BlockStatement
+	Finally *BlockStatement
+}
+
+func (te *TryExpression) expressionNode() {}
+
+//TokenLiteral is of TryExpression
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+
+//Pos is of TryExpression
+func (te *TryExpression) Pos() token.Position { return te.Token.Pos }
+
+//End is of TryExpression
+func (te *TryExpression) End() token.Position {
+	switch {
+	case te.Finally != nil:
+		return te.Finally.End()
+	case te.Catch != nil:
+		return te.Catch.End()
+	case te.Body != nil:
+		return te.Body.End()
+	}
+	return te.Token.End
+}
+
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("try ")
+	out.WriteString(te.Body.String())
+	if te.Catch != nil {
+		out.WriteString(" catch ")
+		if te.Param != nil {
+			out.WriteString("(" + te.Param.String() + ") ")
+		}
+		out.WriteString(te.Catch.String())
+	}
+	if te.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(te.Finally.String())
+	}
+	return out.String()
+}
+
 //BlockStatement is
 type BlockStatement struct {
 	Token      token.Token // the { token
 	Statements []Statement
+	Rbrace     token.Position // position of the closing }
 }
 
 func (bs *BlockStatement) statementNode() {}
 
 //TokenLiteral of BlockStatement
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+//Pos is of BlockStatement
+func (bs *BlockStatement) Pos() token.Position { return bs.Token.Pos }
+
+//End is of BlockStatement
+func (bs *BlockStatement) End() token.Position {
+	if bs.Rbrace.IsValid() {
+		return after(bs.Rbrace)
+	}
+	return bs.Token.End
+}
+
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 	for _, s := range bs.Statements {
@@ -240,6 +721,8 @@ func (bs *BlockStatement) String() string {
 type FunctionLiteral struct {
 	Token      token.Token // The 'fn' token
 	Parameters []*Identifier
+	Defaults   []Expression // parallel to Parameters, nil where there is no default
+	Rest       *Identifier  // the ...rest parameter, or nil
 	Body       *BlockStatement
 }
 
@@ -247,11 +730,29 @@ func (fl *FunctionLiteral) expressionNode() {}
 
 //TokenLiteral is of FunctionLiteral
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+//Pos is of FunctionLiteral
+func (fl *FunctionLiteral) Pos() token.Position { return fl.Token.Pos }
+
+//End is of FunctionLiteral
+func (fl *FunctionLiteral) End() token.Position {
+	if fl.Body != nil {
+		return fl.Body.End()
+	}
+	return fl.Token.End
+}
+
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 	params := []string{}
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+	for i, p := range fl.Parameters {
+		if i < len(fl.Defaults) && fl.Defaults[i] != nil {
+			params = append(params, p.String()+" = "+fl.Defaults[i].String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if fl.Rest != nil {
+		params = append(params, "..."+fl.Rest.String())
 	}
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
@@ -261,17 +762,73 @@ func (fl *FunctionLiteral) String() string {
 	return out.String()
 }
 
+//MacroLiteral is macro(params) { body }. Its body runs during macro
+//expansion, with the unevaluated arguments of each call bound to the
+//parameters as quotes.
+type MacroLiteral struct {
+	Token      token.Token // The 'macro' token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode() {}
+
+//TokenLiteral is of MacroLiteral
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+
+//Pos is of MacroLiteral
+func (ml *MacroLiteral) Pos() token.Position { return ml.Token.Pos }
+
+//End is of MacroLiteral
+func (ml *MacroLiteral) End() token.Position {
+	if ml.Body != nil {
+		return ml.Body.End()
+	}
+	return ml.Token.End
+}
+
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range ml.Parameters {
+		params = append(params, p.String())
+	}
+	out.WriteString(ml.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(ml.Body.String())
+	return out.String()
+}
+
 //CallExpression Function
 type CallExpression struct {
-	Token     token.Token
+	Token     token.Token // the ( token
 	Function  Expression
 	Arguments []Expression
+	Rparen    token.Position // position of the closing )
 }
 
 func (ce *CallExpression) expressionNode() {}
 
 //TokenLiteral is of CallExperession
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+//Pos is of CallExpression
+func (ce *CallExpression) Pos() token.Position {
+	if ce.Function != nil {
+		return ce.Function.Pos()
+	}
+	return ce.Token.Pos
+}
+
+//End is of CallExpression
+func (ce *CallExpression) End() token.Position {
+	if ce.Rparen.IsValid() {
+		return after(ce.Rparen)
+	}
+	return ce.Token.End
+}
+
 func (ce *CallExpression) String() string {
 
 	var out bytes.Buffer
@@ -290,6 +847,140 @@ func (ce *CallExpression) String() string {
 
 }
 
+//NamedArgument is an argument passed by parameter name, as in f(y: 2)
+type NamedArgument struct {
+	Name  *Identifier
+	Value Expression
+}
+
+func (na *NamedArgument) expressionNode()      {}
+func (na *NamedArgument) TokenLiteral() string { return na.Name.TokenLiteral() }
+
+//Pos is of NamedArgument
+func (na *NamedArgument) Pos() token.Position { return na.Name.Pos() }
+
+//End is of NamedArgument
+func (na *NamedArgument) End() token.Position {
+	if na.Value != nil {
+		return na.Value.End()
+	}
+	return na.Name.End()
+}
+
+func (na *NamedArgument) String() string {
+	return na.Name.String() + ": " + na.Value.String()
+}
+
+//MemberExpression reads the member Name of Object, as in m.name for an
+//export of a module
+type MemberExpression struct {
+	Token  token.Token // the . token
+	Object Expression
+	Name   *Identifier
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+
+//Pos is of MemberExpression
+func (me *MemberExpression) Pos() token.Position {
+	if me.Object != nil {
+		return me.Object.Pos()
+	}
+	return me.Token.Pos
+}
+
+//End is of MemberExpression
+func (me *MemberExpression) End() token.Position {
+	if me.Name != nil {
+		return me.Name.End()
+	}
+	return me.Token.End
+}
+
+func (me *MemberExpression) String() string {
+	return me.Object.String() + "." + me.Name.String()
+}
+
+//PipeExpression is Left |> Right. Right is called with the value of Left
+//as its first argument: x |> f(y) is f(x, y) and x |> f is f(x).
+type PipeExpression struct {
+	Token token.Token // the |> token
+	Left  Expression
+	Right Expression
+}
+
+func (pe *PipeExpression) expressionNode()      {}
+func (pe *PipeExpression) TokenLiteral() string { return pe.Token.Literal }
+
+//Pos is of PipeExpression
+func (pe *PipeExpression) Pos() token.Position {
+	if pe.Left != nil {
+		return pe.Left.Pos()
+	}
+	return pe.Token.Pos
+}
+
+//End is of PipeExpression
+func (pe *PipeExpression) End() token.Position {
+	if pe.Right != nil {
+		return pe.Right.End()
+	}
+	return pe.Token.End
+}
+
+func (pe *PipeExpression) String() string {
+	return "(" + pe.Left.String() + " |> " + pe.Right.String() + ")"
+}
+
+//MethodCallExpression is Receiver.Method(Arguments). Method is looked up
+//among the methods of the receiver's type and then in the environment,
+//and is called with the receiver before the arguments.
+type MethodCallExpression struct {
+	Token     token.Token // the . token
+	Receiver  Expression
+	Method    *Identifier
+	Arguments []Expression
+	Rparen    token.Position // position of the closing )
+}
+
+func (mc *MethodCallExpression) expressionNode()      {}
+func (mc *MethodCallExpression) TokenLiteral() string { return mc.Token.Literal }
+
+//Pos is of MethodCallExpression
+func (mc *MethodCallExpression) Pos() token.Position {
+	if mc.Receiver != nil {
+		return mc.Receiver.Pos()
+	}
+	return mc.Token.Pos
+}
+
+//End is of MethodCallExpression
+func (mc *MethodCallExpression) End() token.Position {
+	if mc.Rparen.IsValid() {
+		return after(mc.Rparen)
+	}
+	return mc.Token.End
+}
+
+func (mc *MethodCallExpression) String() string {
+	var out bytes.Buffer
+
+	args := []string{}
+	for _, a := range mc.Arguments {
+		args = append(args, a.String())
+	}
+
+	out.WriteString(mc.Receiver.String())
+	out.WriteString(".")
+	out.WriteString(mc.Method.String())
+	out.WriteString("(")
+	out.WriteString(strings.Join(args, ", "))
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type StringLiteral struct {
 	Token token.Token
 	Value string
@@ -298,20 +989,131 @@ type StringLiteral struct {
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Pos }
+func (sl *StringLiteral) End() token.Position  { return sl.Token.End }
+
+//ArrayLiteral is a list of expressions in brackets, such as [1, x, f(2)]
+type ArrayLiteral struct {
+	Token    token.Token // the [ token
+	Elements []Expression
+	Rbracket token.Position // position of the closing ]
+}
+
+func (al *ArrayLiteral) expressionNode()      {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Position  { return al.Token.Pos }
+func (al *ArrayLiteral) End() token.Position {
+	if al.Rbracket.IsValid() {
+		return after(al.Rbracket)
+	}
+	return al.Token.End
+}
+func (al *ArrayLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+//IndexExpression reads an element of an array or a hash: Left[Index]
+type IndexExpression struct {
+	Token    token.Token // the [ token
+	Left     Expression
+	Index    Expression
+	Rbracket token.Position // position of the closing ]
+}
 
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos
+}
+func (ie *IndexExpression) End() token.Position {
+	if ie.Rbracket.IsValid() {
+		return after(ie.Rbracket)
+	}
+	return ie.Token.End
+}
+func (ie *IndexExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ie.Left.String())
+	out.WriteString("[")
+	out.WriteString(ie.Index.String())
+	out.WriteString("])")
+
+	return out.String()
+}
+
+//InterpolatedString is a string literal with embedded expressions, such as
+//"Hello ${name}!". Parts alternates between the literal text, as
+//StringLiterals, and the embedded expressions, starting and ending with
+//text that may be empty.
+type InterpolatedString struct {
+	Token token.Token // the INTERP_HEAD token
+	Parts []Expression
+}
+
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) Pos() token.Position  { return is.Token.Pos }
+func (is *InterpolatedString) End() token.Position {
+	return is.Parts[len(is.Parts)-1].End()
+}
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+	out.WriteString("\"")
+	for i, part := range is.Parts {
+		if i%2 == 0 {
+			out.WriteString(part.String())
+		} else {
+			out.WriteString("${" + part.String() + "}")
+		}
+	}
+	out.WriteString("\"")
+	return out.String()
+}
+
+//HashLiteral is {key: value, ...}, with its pairs in source order
 type HashLiteral struct {
-	Token token.Token
-	Pairs map[Expression]Expression
+	Token  token.Token // the { token
+	Pairs  []HashPair
+	Rbrace token.Position // position of the closing }
+}
+
+//HashPair is one key: value of a HashLiteral
+type HashPair struct {
+	Key   Expression
+	Value Expression
 }
 
 func (hl *HashLiteral) expressionNode()      {}
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Pos }
+func (hl *HashLiteral) End() token.Position {
+	if hl.Rbrace.IsValid() {
+		return after(hl.Rbrace)
+	}
+	return hl.Token.End
+}
 func (hl *HashLiteral) String() string {
 
 	var out bytes.Buffer
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
 	}
 
 	out.WriteString("{")
@@ -321,3 +1123,57 @@ func (hl *HashLiteral) String() string {
 	return out.String()
 
 }
+
+//MatchExpression evaluates the Body of the first arm whose pattern
+//matches Subject and whose guard, if any, holds:
+//match (x) { 0 => "zero", [a, ...] if a > 0 => a, _ => x }
+type MatchExpression struct {
+	Token   token.Token // the 'match' token
+	Subject Expression
+	Arms    []*MatchArm
+	Rbrace  token.Position // position of the closing }
+}
+
+//MatchArm is one pattern => body case of a MatchExpression
+type MatchArm struct {
+	Pattern Pattern
+	Guard   Expression // the condition after if, or nil
+	Body    Expression
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) Pos() token.Position  { return me.Token.Pos }
+func (me *MatchExpression) End() token.Position {
+	if me.Rbrace.IsValid() {
+		return after(me.Rbrace)
+	}
+	return me.Token.End
+}
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		s := arm.Pattern.String()
+		if arm.Guard != nil {
+			s += " if " + arm.Guard.String()
+		}
+		arms = append(arms, s+" => "+arm.Body.String())
+	}
+
+	out.WriteString("match (")
+	out.WriteString(me.Subject.String())
+	out.WriteString(") { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+//after is the position just past a single-byte delimiter at p
+func after(p token.Position) token.Position {
+	p.Offset++
+	p.Column++
+	return p
+}
@@ -2,6 +2,7 @@ package ast
 
 import (
 	"OSPLang/token"
+	"reflect"
 	"testing"
 )
 
@@ -25,3 +26,118 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Value: 2} }
+
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok || integer.Value != 1 {
+			return node
+		}
+		return &IntegerLiteral{Value: 2}
+	}
+
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{one(), two()},
+		{
+			&Program{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			&Program{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+		},
+		{
+			&InfixExpression{Left: one(), Operator: "+", Right: one()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&PrefixExpression{Operator: "-", Right: one()},
+			&PrefixExpression{Operator: "-", Right: two()},
+		},
+		{
+			&IndexExpression{Left: one(), Index: one()},
+			&IndexExpression{Left: two(), Index: two()},
+		},
+		{
+			&IfExpression{
+				Condition:   one(),
+				Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			},
+			&IfExpression{
+				Condition:   two(),
+				Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+			},
+		},
+		{
+			&ReturnStatement{ReturnValue: one()},
+			&ReturnStatement{ReturnValue: two()},
+		},
+		{
+			&LetStatement{Name: &Identifier{Value: "x"}, Value: one()},
+			&LetStatement{Name: &Identifier{Value: "x"}, Value: two()},
+		},
+		{
+			&FunctionLiteral{
+				Parameters: []*Identifier{},
+				Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			},
+			&FunctionLiteral{
+				Parameters: []*Identifier{},
+				Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+			},
+		},
+		{
+			&ArrayLiteral{Elements: []Expression{one(), one()}},
+			&ArrayLiteral{Elements: []Expression{two(), two()}},
+		},
+		{
+			&WhileStatement{Condition: one(), Body: &BlockStatement{}},
+			&WhileStatement{Condition: two(), Body: &BlockStatement{Statements: []Statement{}}},
+		},
+		{
+			&AssignExpression{Target: &Identifier{Value: "x"}, Operator: "=", Value: one()},
+			&AssignExpression{Target: &Identifier{Value: "x"}, Operator: "=", Value: two()},
+		},
+		{
+			&MatchExpression{
+				Subject: one(),
+				Arms: []*MatchArm{{
+					Pattern: &ArrayPattern{
+						Elements: []Pattern{&LiteralPattern{Value: one()}},
+						Rest:     &BindingPattern{Name: &Identifier{Value: "rest"}},
+					},
+					Guard: one(),
+					Body:  one(),
+				}},
+			},
+			&MatchExpression{
+				Subject: two(),
+				Arms: []*MatchArm{{
+					Pattern: &ArrayPattern{
+						Elements: []Pattern{&LiteralPattern{Value: two()}},
+						Rest:     &BindingPattern{Name: &Identifier{Value: "rest"}},
+					},
+					Guard: two(),
+					Body:  two(),
+				}},
+			},
+		},
+		{
+			&HashLiteral{Pairs: []HashPair{{Key: one(), Value: one()}}},
+			&HashLiteral{Pairs: []HashPair{{Key: two(), Value: two()}}},
+		},
+	}
+
+	for i, tt := range tests {
+		before := tt.input.String()
+		modified := Modify(tt.input, turnOneIntoTwo)
+		if tt.input.String() != before {
+			t.Errorf("tests[%d] - input was changed. got=%q, was=%q", i, tt.input.String(), before)
+		}
+		if !reflect.DeepEqual(modified, tt.expected) {
+			t.Errorf("tests[%d] - not equal. got=%#v, want=%#v", i, modified, tt.expected)
+		}
+	}
+}
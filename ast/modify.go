@@ -0,0 +1,298 @@
+package ast
+
+//ModifierFunc is applied by Modify to each node of a tree
+type ModifierFunc func(Node) Node
+
+//Modify returns a copy of node in which every node has been passed
+//through modifier, children before their parents. node itself is left
+//untouched, so a tree can be modified more than once; leaves that the
+//modifier returns as they are, such as identifiers and literals, are
+//shared with it. A child that the modifier replaces with a node of the
+//wrong kind, or with nil, becomes nil; a statement is dropped instead.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+
+	case *Program:
+		n := *node
+		n.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&n)
+
+	case *ExpressionStatement:
+		n := *node
+		n.Expression = modifyExpression(node.Expression, modifier)
+		return modifier(&n)
+
+	case *LetStatement:
+		n := *node
+		n.Name = modifyIdentifier(node.Name, modifier)
+		n.Pattern = modifyPattern(node.Pattern, modifier)
+		n.Value = modifyExpression(node.Value, modifier)
+		return modifier(&n)
+
+	case *ImportStatement:
+		n := *node
+		n.Alias = modifyIdentifier(node.Alias, modifier)
+		return modifier(&n)
+
+	case *ExportStatement:
+		n := *node
+		if node.Statement != nil {
+			n.Statement, _ = Modify(node.Statement, modifier).(*LetStatement)
+		}
+		return modifier(&n)
+
+	case *ReturnStatement:
+		n := *node
+		n.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+		return modifier(&n)
+
+	case *StructStatement:
+		n := *node
+		n.Name = modifyIdentifier(node.Name, modifier)
+		// field and method names are not variables, so they stay as they are
+		n.Methods = make([]*StructMethod, len(node.Methods))
+		for i, method := range node.Methods {
+			m := *method
+			m.Function, _ = Modify(method.Function, modifier).(*FunctionLiteral)
+			n.Methods[i] = &m
+		}
+		return modifier(&n)
+
+	case *ThrowStatement:
+		n := *node
+		n.Value = modifyExpression(node.Value, modifier)
+		return modifier(&n)
+
+	case *BlockStatement:
+		n := *node
+		n.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&n)
+
+	case *WhileStatement:
+		n := *node
+		n.Condition = modifyExpression(node.Condition, modifier)
+		n.Body = modifyBlock(node.Body, modifier)
+		return modifier(&n)
+
+	case *ForInStatement:
+		n := *node
+		n.Key = modifyIdentifier(node.Key, modifier)
+		n.Value = modifyIdentifier(node.Value, modifier)
+		n.Iterable = modifyExpression(node.Iterable, modifier)
+		n.Body = modifyBlock(node.Body, modifier)
+		return modifier(&n)
+
+	case *PrefixExpression:
+		n := *node
+		n.Right = modifyExpression(node.Right, modifier)
+		return modifier(&n)
+
+	case *InfixExpression:
+		n := *node
+		n.Left = modifyExpression(node.Left, modifier)
+		n.Right = modifyExpression(node.Right, modifier)
+		return modifier(&n)
+
+	case *AssignExpression:
+		n := *node
+		n.Target = modifyExpression(node.Target, modifier)
+		n.Value = modifyExpression(node.Value, modifier)
+		return modifier(&n)
+
+	case *IfExpression:
+		n := *node
+		n.Condition = modifyExpression(node.Condition, modifier)
+		n.Consequence = modifyBlock(node.Consequence, modifier)
+		n.Alternative = modifyBlock(node.Alternative, modifier)
+		return modifier(&n)
+
+	case *TryExpression:
+		n := *node
+		n.Body = modifyBlock(node.Body, modifier)
+		n.Param = modifyIdentifier(node.Param, modifier)
+		n.Catch = modifyBlock(node.Catch, modifier)
+		n.Finally = modifyBlock(node.Finally, modifier)
+		return modifier(&n)
+
+	case *FunctionLiteral:
+		n := *node
+		n.Parameters = modifyIdentifiers(node.Parameters, modifier)
+		n.Defaults = modifyExpressions(node.Defaults, modifier)
+		n.Rest = modifyIdentifier(node.Rest, modifier)
+		n.Body = modifyBlock(node.Body, modifier)
+		return modifier(&n)
+
+	case *MacroLiteral:
+		n := *node
+		n.Parameters = modifyIdentifiers(node.Parameters, modifier)
+		n.Body = modifyBlock(node.Body, modifier)
+		return modifier(&n)
+
+	case *CallExpression:
+		n := *node
+		n.Function = modifyExpression(node.Function, modifier)
+		n.Arguments = modifyExpressions(node.Arguments, modifier)
+		return modifier(&n)
+
+	case *MemberExpression:
+		// like a method name, the member name is not a variable
+		n := *node
+		n.Object = modifyExpression(node.Object, modifier)
+		return modifier(&n)
+
+	case *PipeExpression:
+		n := *node
+		n.Left = modifyExpression(node.Left, modifier)
+		n.Right = modifyExpression(node.Right, modifier)
+		return modifier(&n)
+
+	case *MethodCallExpression:
+		// the method name is looked up at run time, not as a variable
+		n := *node
+		n.Receiver = modifyExpression(node.Receiver, modifier)
+		n.Arguments = modifyExpressions(node.Arguments, modifier)
+		return modifier(&n)
+
+	case *NamedArgument:
+		// the name is a label for a parameter, not a use of a variable
+		n := *node
+		n.Value = modifyExpression(node.Value, modifier)
+		return modifier(&n)
+
+	case *ArrayLiteral:
+		n := *node
+		n.Elements = modifyExpressions(node.Elements, modifier)
+		return modifier(&n)
+
+	case *IndexExpression:
+		n := *node
+		n.Left = modifyExpression(node.Left, modifier)
+		n.Index = modifyExpression(node.Index, modifier)
+		return modifier(&n)
+
+	case *InterpolatedString:
+		n := *node
+		n.Parts = modifyExpressions(node.Parts, modifier)
+		return modifier(&n)
+
+	case *HashLiteral:
+		n := *node
+		n.Pairs = make([]HashPair, len(node.Pairs))
+		for i, pair := range node.Pairs {
+			n.Pairs[i] = HashPair{
+				Key:   modifyExpression(pair.Key, modifier),
+				Value: modifyExpression(pair.Value, modifier),
+			}
+		}
+		return modifier(&n)
+
+	case *MatchExpression:
+		n := *node
+		n.Subject = modifyExpression(node.Subject, modifier)
+		n.Arms = make([]*MatchArm, len(node.Arms))
+		for i, arm := range node.Arms {
+			n.Arms[i] = &MatchArm{
+				Pattern: modifyPattern(arm.Pattern, modifier),
+				Guard:   modifyExpression(arm.Guard, modifier),
+				Body:    modifyExpression(arm.Body, modifier),
+			}
+		}
+		return modifier(&n)
+
+	case *BindingPattern:
+		n := *node
+		n.Name = modifyIdentifier(node.Name, modifier)
+		return modifier(&n)
+
+	case *LiteralPattern:
+		n := *node
+		n.Value = modifyExpression(node.Value, modifier)
+		return modifier(&n)
+
+	case *ArrayPattern:
+		n := *node
+		n.Elements = make([]Pattern, len(node.Elements))
+		for i, el := range node.Elements {
+			n.Elements[i] = modifyPattern(el, modifier)
+		}
+		n.Rest = modifyPattern(node.Rest, modifier)
+		return modifier(&n)
+
+	case *HashPattern:
+		n := *node
+		n.Fields = make([]*HashPatternField, len(node.Fields))
+		for i, field := range node.Fields {
+			n.Fields[i] = &HashPatternField{
+				Key:   modifyExpression(field.Key, modifier),
+				Value: modifyPattern(field.Value, modifier),
+			}
+		}
+		return modifier(&n)
+	}
+
+	return modifier(node)
+}
+
+func modifyExpression(exp Expression, modifier ModifierFunc) Expression {
+	if exp == nil {
+		return nil
+	}
+	modified, _ := Modify(exp, modifier).(Expression)
+	return modified
+}
+
+func modifyExpressions(exps []Expression, modifier ModifierFunc) []Expression {
+	if exps == nil {
+		return nil
+	}
+	modified := make([]Expression, len(exps))
+	for i, exp := range exps {
+		modified[i] = modifyExpression(exp, modifier)
+	}
+	return modified
+}
+
+func modifyPattern(pattern Pattern, modifier ModifierFunc) Pattern {
+	if pattern == nil {
+		return nil
+	}
+	modified, _ := Modify(pattern, modifier).(Pattern)
+	return modified
+}
+
+func modifyStatements(stmts []Statement, modifier ModifierFunc) []Statement {
+	modified := make([]Statement, 0, len(stmts))
+	for _, stmt := range stmts {
+		if s, ok := Modify(stmt, modifier).(Statement); ok {
+			modified = append(modified, s)
+		}
+	}
+	return modified
+}
+
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	modified, _ := Modify(block, modifier).(*BlockStatement)
+	return modified
+}
+
+func modifyIdentifier(ident *Identifier, modifier ModifierFunc) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	modified, _ := Modify(ident, modifier).(*Identifier)
+	return modified
+}
+
+func modifyIdentifiers(idents []*Identifier, modifier ModifierFunc) []*Identifier {
+	if idents == nil {
+		return nil
+	}
+	modified := make([]*Identifier, len(idents))
+	for i, ident := range idents {
+		modified[i] = modifyIdentifier(ident, modifier)
+	}
+	return modified
+}
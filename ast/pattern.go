@@ -0,0 +1,130 @@
+package ast
+
+import (
+	"OSPLang/token"
+	"bytes"
+	"strings"
+)
+
+//Pattern describes the shape of a value, and the names to bind to its
+//parts, in a match arm
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+//WildcardPattern is _, which matches any value and binds nothing
+type WildcardPattern struct {
+	Token token.Token // the _ token
+}
+
+func (wp *WildcardPattern) patternNode()         {}
+func (wp *WildcardPattern) TokenLiteral() string { return wp.Token.Literal }
+func (wp *WildcardPattern) String() string       { return "_" }
+func (wp *WildcardPattern) Pos() token.Position  { return wp.Token.Pos }
+func (wp *WildcardPattern) End() token.Position  { return wp.Token.End }
+
+//BindingPattern matches any value and binds it to Name
+type BindingPattern struct {
+	Name *Identifier
+}
+
+func (bp *BindingPattern) patternNode()         {}
+func (bp *BindingPattern) TokenLiteral() string { return bp.Name.TokenLiteral() }
+func (bp *BindingPattern) String() string       { return bp.Name.String() }
+func (bp *BindingPattern) Pos() token.Position  { return bp.Name.Pos() }
+func (bp *BindingPattern) End() token.Position  { return bp.Name.End() }
+
+//LiteralPattern matches the values equal to a number, string or boolean
+//literal, such as 1, -2.5, "a" or true
+type LiteralPattern struct {
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode()         {}
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Value.TokenLiteral() }
+func (lp *LiteralPattern) Pos() token.Position  { return lp.Value.Pos() }
+func (lp *LiteralPattern) End() token.Position  { return lp.Value.End() }
+func (lp *LiteralPattern) String() string {
+	if _, ok := lp.Value.(*StringLiteral); ok {
+		return "\"" + lp.Value.String() + "\""
+	}
+	return lp.Value.String()
+}
+
+//ArrayPattern matches an array element by element, as in [a, _, 3]. With
+//a Rest, as in [first, ...rest], the array may be longer than Elements
+//and Rest is matched against an array of the remaining elements.
+type ArrayPattern struct {
+	Token    token.Token // the [ token
+	Elements []Pattern
+	Rest     Pattern        // a BindingPattern or WildcardPattern, or nil
+	Rbracket token.Position // position of the closing ]
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) Pos() token.Position  { return ap.Token.Pos }
+func (ap *ArrayPattern) End() token.Position {
+	if ap.Rbracket.IsValid() {
+		return after(ap.Rbracket)
+	}
+	return ap.Token.End
+}
+func (ap *ArrayPattern) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range ap.Elements {
+		elements = append(elements, el.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+//HashPattern matches a hash that has every key of Fields, each value
+//matching its pattern. Other keys are allowed. A field written as a bare
+//name, as in {name, age: years}, binds the value of the key "name" to
+//name; a name before a colon stands for a string key too.
+type HashPattern struct {
+	Token  token.Token // the { token
+	Fields []*HashPatternField
+	Rbrace token.Position // position of the closing }
+}
+
+//HashPatternField is one key: pattern part of a HashPattern
+type HashPatternField struct {
+	Key   Expression // a StringLiteral, IntegerLiteral or Boolean
+	Value Pattern
+}
+
+func (hp *HashPattern) patternNode()         {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) Pos() token.Position  { return hp.Token.Pos }
+func (hp *HashPattern) End() token.Position {
+	if hp.Rbrace.IsValid() {
+		return after(hp.Rbrace)
+	}
+	return hp.Token.End
+}
+func (hp *HashPattern) String() string {
+	var out bytes.Buffer
+
+	fields := []string{}
+	for _, field := range hp.Fields {
+		fields = append(fields, field.Key.String()+": "+field.Value.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
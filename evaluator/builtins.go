@@ -6,16 +6,47 @@ var builtins = map[string]*object.Builtin{
 	"len": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of argument. go=%d,want=1", len(args))
+				return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
 			}
 
 			switch arg := args[0].(type) {
 			case *object.String:
 				return &object.Integer{Value: int64(len(arg.Value))}
+			case *object.Array:
+				return &object.Integer{Value: int64(len(arg.Elements))}
 			default:
-				return newError("argument to 'len' not supported, got %s", args[0].Type())
+				return newKindError(object.TYPE_ERROR, "argument to `len` not supported, got %s", args[0].Type())
 			}
 
 		},
 	},
+	//error(message, kind, cause) makes an error value to throw. The kind
+	//defaults to "Error"; the cause is an error value or null.
+	"error": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 3 {
+				return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1 to 3", len(args))
+			}
+			for i, arg := range args {
+				if i < 2 && typeOf(arg) != object.STRING_OBJ {
+					return newKindError(object.TYPE_ERROR, "argument %d to `error` must be STRING, got %s", i+1, typeOf(arg))
+				}
+			}
+
+			err := &object.Error{Message: args[0].(*object.String).Value, Kind: object.GENERIC_ERROR}
+			if len(args) > 1 {
+				err.Kind = args[1].(*object.String).Value
+			}
+			if len(args) > 2 {
+				switch cause := args[2].(type) {
+				case *object.ErrorValue:
+					err.Cause = cause.Error
+				case *object.Null:
+				default:
+					return newKindError(object.TYPE_ERROR, "cause given to `error` must be ERROR_VALUE or NULL, got %s", typeOf(cause))
+				}
+			}
+			return &object.ErrorValue{Error: err}
+		},
+	},
 }
@@ -4,16 +4,30 @@ import (
 	"OSPLang/ast"
 	"OSPLang/object"
 	"fmt"
+	"sort"
+	"strings"
 )
 
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL     = &object.Null{}
+	TRUE     = &object.Boolean{Value: true}
+	FALSE    = &object.Boolean{Value: false}
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
 //Eval ...
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := eval(node, env)
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+	return result
+}
+
+//eval does the work of Eval; errors it returns are stamped with the
+//position of the innermost node that produced them
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
@@ -22,24 +36,29 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return Eval(node.Expression, env)
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.Boolean:
 		return nativeBoolToBoolanObject(node.Value)
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if isAbrupt(right) {
 			return right
 		}
 		return evalPrefixExpression(node.Operator, right)
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
 		left := Eval(node.Left, env)
 
-		if isError(left) {
+		if isAbrupt(left) {
 			return left
 		}
 
 		right := Eval(node.Right, env)
 
-		if isError(right) {
+		if isAbrupt(right) {
 			return right
 		}
 
@@ -51,15 +70,32 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
-		if isError(val) {
+		if isAbrupt(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+	case *ast.ForInStatement:
+		return evalForInStatement(node, env)
+	case *ast.StructStatement:
+		return evalStructStatement(node, env)
+	case *ast.ThrowStatement:
+		return evalThrowStatement(node, env)
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
-		if isError(val) {
+		if isAbrupt(val) {
 			return val
 		}
+		if node.Pattern != nil {
+			return evalDestructuring(node.Pattern, val, env)
+		}
 		env.Set(node.Name.Value, val)
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
@@ -67,23 +103,63 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{Parameters: params, Defaults: node.Defaults, Rest: node.Rest, Env: env, Body: body}
+	case *ast.MacroLiteral:
+		return newError("macros can only be defined by a top-level let")
 	case *ast.CallExpression:
+		if isCallTo(node, "quote") {
+			if len(node.Arguments) != 1 {
+				return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments to quote. got=%d, want=1", len(node.Arguments))
+			}
+			return quote(node.Arguments[0], env)
+		}
 		function := Eval(node.Function, env)
-		if isError(function) {
+		if isAbrupt(function) {
 			return function
 		}
 
-		args := evalExpression(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+		args, named, err := evalArguments(node.Arguments, env)
+		if err != nil {
+			return err
 		}
 
-		return applyFunction(function, args)
+		return applyFunction(function, args, named)
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
+	case *ast.ArrayLiteral:
+		elements := evalExpression(node.Elements, env)
+		if len(elements) == 1 && isAbrupt(elements[0]) {
+			return elements[0]
+		}
+		return &object.Array{Elements: elements}
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+	case *ast.IndexExpression:
+		left := Eval(node.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+		index := Eval(node.Index, env)
+		if isAbrupt(index) {
+			return index
+		}
+		return evalIndexExpression(left, index)
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+	case *ast.ImportStatement:
+		return evalImportStatement(node, env)
+	case *ast.ExportStatement:
+		return Eval(node.Statement, env)
+	case *ast.MemberExpression:
+		return evalMemberExpression(node, env)
+	case *ast.PipeExpression:
+		return evalPipeExpression(node, env)
+	case *ast.MethodCallExpression:
+		return evalMethodCallExpression(node, env)
 	}
 	return nil
 }
@@ -118,7 +194,7 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 	case "-":
 		return evalMinusOperatorExpression(right)
 	default:
-		return newError("unkown operator: %s%s", operator, right.Type())
+		return newKindError(object.TYPE_ERROR, "unkown operator: %s%s", operator, right.Type())
 	}
 }
 
@@ -137,28 +213,35 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusOperatorExpression(right object.Object) object.Object {
-
-	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: -right.Value}
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
+		return newKindError(object.TYPE_ERROR, "unknown operator: -%s", right.Type())
 	}
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
+	case left.Type() == object.INSTANCE_OBJ && right.Type() == object.INSTANCE_OBJ && (operator == "==" || operator == "!="):
+		equal := instancesEqual(left.(*object.Instance), right.(*object.Instance))
+		return nativeBoolToBoolanObject(equal == (operator == "=="))
 	case operator == "==":
 		return nativeBoolToBoolanObject(left == right)
 	case operator == "!=":
 		return nativeBoolToBoolanObject(left != right)
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return newKindError(object.TYPE_ERROR, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newKindError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -174,26 +257,105 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newKindError(object.ARITHMETIC_ERROR, "division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case "<":
 		return nativeBoolToBoolanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBoolanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBoolanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBoolanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBoolanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBoolanObject(leftVal != rightVal)
+
+	default:
+		return newKindError(object.TYPE_ERROR, "unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+
+}
+
+//evalFloatInfixExpression handles arithmetic and comparison where at
+//least one operand is a float. An integer operand is promoted to float
+//and the result of arithmetic is always a float; division by zero
+//follows IEEE 754 and yields an infinity or NaN.
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "<":
+		return nativeBoolToBoolanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBoolanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBoolanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBoolanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBoolanObject(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToBoolanObject(leftVal != rightVal)
 
 	default:
-		return newError("unknown operator: %s %s %s",
+		return newKindError(object.TYPE_ERROR, "unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.Float:
+		return obj.Value
+	}
+	return 0
+}
+
+//evalLogicalExpression evaluates && and || from left to right, only
+//evaluating the right side when the left does not decide the result.
+//The result is always a boolean.
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+	if node.Operator == "&&" && !isTruthy(left) {
+		return FALSE
+	}
+	if node.Operator == "||" && isTruthy(left) {
+		return TRUE
+	}
 
+	right := Eval(node.Right, env)
+	if isAbrupt(right) {
+		return right
+	}
+	return nativeBoolToBoolanObject(isTruthy(right))
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
 	condition := Eval(ie.Condition, env)
-	if isError(condition) {
+	if isAbrupt(condition) {
 		return condition
 	}
 	if isTruthy(condition) {
@@ -218,29 +380,46 @@ func isTruthy(obj object.Object) bool {
 	}
 }
 
+//evalBlockStatement returns the value of the last statement of block, or
+//NULL if the block is empty or ends with a statement that has no value,
+//such as a let. Function bodies, branches and the like are blocks, so
+//their values are never nil.
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 	for _, statement := range block.Statements {
 		result = Eval(statement, env)
 
-		if result != nil {
-
-			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
-				return result
-			}
+		if isAbrupt(result) {
+			return result
 		}
 	}
+	if result == nil {
+		return NULL
+	}
 	return result
 }
 
 func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+	return newKindError(object.GENERIC_ERROR, format, a...)
 }
 
-func isError(obj object.Object) bool {
-	if obj != nil {
-		return obj.Type() == object.ERROR_OBJ
+//newKindError returns an error of the given kind, one of the kinds
+//declared in package object
+func newKindError(kind string, format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...), Kind: kind}
+}
+
+//isAbrupt reports whether obj cuts the evaluation of its enclosing
+//expression short: an error, or the signal of a return, break or
+//continue, which must reach the function or loop it is meant for even
+//from inside an expression such as let x = if (c) { break }
+func isAbrupt(obj object.Object) bool {
+	if obj == nil {
+		return false
+	}
+	switch obj.Type() {
+	case object.ERROR_OBJ, object.RETURN_VALUE_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+		return true
 	}
 	return false
 }
@@ -254,7 +433,7 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 		return builtin
 	}
 
-	return newError("identifier not found: " + node.Value)
+	return newKindError(object.NAME_ERROR, "identifier not found: %s", node.Value)
 }
 
 func evalExpression(exps []ast.Expression, env *object.Environment) []object.Object {
@@ -262,7 +441,7 @@ func evalExpression(exps []ast.Expression, env *object.Environment) []object.Obj
 
 	for _, e := range exps {
 		evaluated := Eval(e, env)
-		if isError(evaluated) {
+		if isAbrupt(evaluated) {
 			return []object.Object{evaluated}
 		}
 		result = append(result, evaluated)
@@ -272,29 +451,147 @@ func evalExpression(exps []ast.Expression, env *object.Environment) []object.Obj
 
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+//evalArguments evaluates the arguments of a call from left to right into
+//the positional ones and the named ones
+func evalArguments(exps []ast.Expression, env *object.Environment) ([]object.Object, map[string]object.Object, object.Object) {
+	var args []object.Object
+	var named map[string]object.Object
+
+	for _, e := range exps {
+		arg, ok := e.(*ast.NamedArgument)
+		if !ok {
+			evaluated := Eval(e, env)
+			if isAbrupt(evaluated) {
+				return nil, nil, evaluated
+			}
+			args = append(args, evaluated)
+			continue
+		}
+
+		if _, ok := named[arg.Name.Value]; ok {
+			err := newKindError(object.ARGUMENT_ERROR, "argument %s given more than once", arg.Name.Value)
+			err.Pos = arg.Pos()
+			return nil, nil, err
+		}
+		evaluated := Eval(arg.Value, env)
+		if isAbrupt(evaluated) {
+			return nil, nil, evaluated
+		}
+		if named == nil {
+			named = map[string]object.Object{}
+		}
+		named[arg.Name.Value] = evaluated
+	}
+	return args, named, nil
+}
+
+//applyFunction calls fn with positional arguments args and the named
+//arguments in named, which may be nil
+func applyFunction(fn object.Object, args []object.Object, named map[string]object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
+		extendedEnv, err := extendFunctionEnv(fn, args, named)
+		if err != nil {
+			return err
+		}
 		evaluated := Eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 
 	case *object.Builtin:
+		if len(named) > 0 {
+			return newKindError(object.ARGUMENT_ERROR, "builtin functions take no named arguments")
+		}
 		return fn.Fn(args...)
 
+	case *object.Struct:
+		return newInstance(fn, args, named)
+
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newKindError(object.TYPE_ERROR, "not a function: %s", fn.Type())
 
 	}
 
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+//extendFunctionEnv binds the parameters of fn for a call. Parameters are
+//filled from args in order, then by name from named, then from their
+//defaults, which are evaluated in the new environment and so can refer
+//to the parameters before them. Arguments beyond the parameters go to
+//the rest parameter as an array.
+func extendFunctionEnv(fn *object.Function, args []object.Object, named map[string]object.Object) (*object.Environment, *object.Error) {
 	env := object.NewEnclosedEnvironment(fn.Env)
-	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+
+	if len(args) > len(fn.Parameters) && fn.Rest == nil {
+		return nil, arityError(fn, len(args)+len(named))
+	}
+	names := make([]string, 0, len(named))
+	for name := range named {
+		names = append(names, name)
 	}
-	return env
+	sort.Strings(names)
+	for _, name := range names {
+		if !hasParameter(fn, name) {
+			return nil, newKindError(object.ARGUMENT_ERROR, "unexpected named argument %s", name)
+		}
+	}
+
+	for i, param := range fn.Parameters {
+		val, isNamed := named[param.Value]
+		switch {
+		case i < len(args):
+			if isNamed {
+				return nil, newKindError(object.ARGUMENT_ERROR, "argument %s given more than once", param.Value)
+			}
+			val = args[i]
+		case isNamed:
+		case i < len(fn.Defaults) && fn.Defaults[i] != nil:
+			val = Eval(fn.Defaults[i], env)
+			if err, ok := val.(*object.Error); ok {
+				return nil, err
+			}
+		case len(named) > 0:
+			return nil, newKindError(object.ARGUMENT_ERROR, "missing argument for parameter %s", param.Value)
+		default:
+			return nil, arityError(fn, len(args))
+		}
+		env.Set(param.Value, val)
+	}
+
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		env.Set(fn.Rest.Value, &object.Array{Elements: rest})
+	}
+	return env, nil
+}
+
+func hasParameter(fn *object.Function, name string) bool {
+	for _, param := range fn.Parameters {
+		if param.Value == name {
+			return true
+		}
+	}
+	return false
+}
+
+//arityError reports a call of fn with the wrong number of arguments
+func arityError(fn *object.Function, got int) *object.Error {
+	required := 0
+	for i := range fn.Parameters {
+		if i >= len(fn.Defaults) || fn.Defaults[i] == nil {
+			required = i + 1
+		}
+	}
+	want := fmt.Sprintf("%d", len(fn.Parameters))
+	switch {
+	case fn.Rest != nil:
+		want = fmt.Sprintf("at least %d", required)
+	case required < len(fn.Parameters):
+		want = fmt.Sprintf("%d to %d", required, len(fn.Parameters))
+	}
+	return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=%s", got, want)
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -306,7 +603,7 @@ func unwrapReturnValue(obj object.Object) object.Object {
 
 func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
 	if operator != "+" {
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newKindError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 
 	leftVal := left.(*object.String).Value
@@ -315,29 +612,250 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 
 }
 
+//evalInterpolatedString joins the text of the string with the display
+//form of each embedded expression's value
+func evalInterpolatedString(node *ast.InterpolatedString, env *object.Environment) object.Object {
+	var out strings.Builder
+	for _, part := range node.Parts {
+		val := Eval(part, env)
+		if isAbrupt(val) {
+			return val
+		}
+		if val == nil {
+			val = NULL
+		}
+		out.WriteString(val.Inspect())
+	}
+	return &object.String{Value: out.String()}
+}
+
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
-		if isError(key) {
+	for _, pair := range node.Pairs {
+		key := Eval(pair.Key, env)
+		if isAbrupt(key) {
 			return key
 		}
 
 		hashKey, ok := key.(object.Hashable)
 		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+			return newKindError(object.TYPE_ERROR, "unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
-		if isError(value) {
+		value := Eval(pair.Value, env)
+		if isAbrupt(value) {
 			return value
 		}
 
 		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashed, object.HashPair{Key: key, Value: value})
+
+	}
+
+	return hash
+}
+
+//evalIndexExpression reads an element of an array or a hash. An index
+//outside the array, negative ones included, and a key missing from the
+//hash both give null.
+func evalIndexExpression(left, index object.Object) object.Object {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.HASH_OBJ:
+		return evalHashIndexExpression(left, index)
+	default:
+		return newKindError(object.TYPE_ERROR, "index operator not supported: %s[%s]", left.Type(), index.Type())
+	}
+}
+
+func evalArrayIndexExpression(array, index object.Object) object.Object {
+	elements := array.(*object.Array).Elements
+	idx := index.(*object.Integer).Value
+	if idx < 0 || idx >= int64(len(elements)) {
+		return NULL
+	}
+	return elements[idx]
+}
+
+func evalHashIndexExpression(hash, index object.Object) object.Object {
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return newKindError(object.TYPE_ERROR, "unusable as hash key: %s", index.Type())
+	}
+	pair, ok := hash.(*object.Hash).Get(key.HashKey())
+	if !ok {
+		return NULL
+	}
+	return pair.Value
+}
 
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(ws.Condition, env)
+		if isAbrupt(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+		if result, done := loopExit(Eval(ws.Body, env)); done {
+			return result
+		}
 	}
+}
 
-	return &object.Hash{Pairs: pairs}
+//evalForInStatement runs the body for each character of a string,
+//element of an array or pair of a hash, in insertion order. Each
+//iteration binds the loop names in an environment of its own, so
+//closures made in the body keep their values.
+func evalForInStatement(fs *ast.ForInStatement, env *object.Environment) object.Object {
+	iterable := Eval(fs.Iterable, env)
+	if isAbrupt(iterable) {
+		return iterable
+	}
+
+	var keys, values []object.Object
+	switch iterable := iterable.(type) {
+	case *object.String:
+		i := 0
+		for _, ch := range iterable.Value {
+			keys = append(keys, &object.Integer{Value: int64(i)})
+			values = append(values, &object.String{Value: string(ch)})
+			i++
+		}
+	case *object.Array:
+		for i, el := range iterable.Elements {
+			keys = append(keys, &object.Integer{Value: int64(i)})
+			values = append(values, el)
+		}
+	case *object.Hash:
+		for _, pair := range iterable.Ordered() {
+			keys = append(keys, pair.Key)
+			values = append(values, pair.Value)
+		}
+	default:
+		return newKindError(object.TYPE_ERROR, "cannot iterate over %s", typeOf(iterable))
+	}
+
+	for i, value := range values {
+		loopEnv := object.NewEnclosedEnvironment(env)
+		if fs.Key != nil {
+			loopEnv.Set(fs.Key.Value, keys[i])
+		} else if iterable.Type() == object.HASH_OBJ {
+			value = &object.Array{Elements: []object.Object{keys[i], value}}
+		}
+		loopEnv.Set(fs.Value.Value, value)
+		if result, done := loopExit(Eval(fs.Body, loopEnv)); done {
+			return result
+		}
+	}
+	return NULL
+}
+
+//loopExit reports whether a loop is done after its body gave result, and
+//if so what the loop evaluates to
+func loopExit(result object.Object) (object.Object, bool) {
+	if result == nil {
+		return nil, false
+	}
+	switch result.Type() {
+	case object.BREAK_OBJ:
+		return NULL, true
+	case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+		return result, true
+	}
+	return nil, false
+}
+
+//evalAssignExpression stores a value in a variable, an array element or a
+//hash entry and returns it. A compound assignment such as x += 1 first
+//applies its operator to the current value.
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	val := Eval(node.Value, env)
+	if isAbrupt(val) {
+		return val
+	}
+
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		if node.Operator != "=" {
+			current := evalIdentifier(target, env)
+			if isAbrupt(current) {
+				return current
+			}
+			if val = applyCompound(node.Operator, current, val); isAbrupt(val) {
+				return val
+			}
+		}
+		if !env.Assign(target.Value, val) {
+			return newKindError(object.NAME_ERROR, "assignment to undeclared identifier: %s", target.Value)
+		}
+		return val
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+		index := Eval(target.Index, env)
+		if isAbrupt(index) {
+			return index
+		}
+		if node.Operator != "=" {
+			current := evalIndexExpression(left, index)
+			if isAbrupt(current) {
+				return current
+			}
+			if val = applyCompound(node.Operator, current, val); isAbrupt(val) {
+				return val
+			}
+		}
+		return evalIndexAssignment(left, index, val)
+	case *ast.MemberExpression:
+		obj := Eval(target.Object, env)
+		if isAbrupt(obj) {
+			return obj
+		}
+		if instance, ok := obj.(*object.Instance); ok && node.Operator != "=" {
+			if current, ok := instance.Fields[target.Name.Value]; ok {
+				if val = applyCompound(node.Operator, current, val); isAbrupt(val) {
+					return val
+				}
+			}
+		}
+		return evalFieldAssignment(obj, target.Name.Value, val)
+	}
+	return newKindError(object.TYPE_ERROR, "cannot assign to %s", node.Target.String())
+}
+
+//applyCompound applies the operator of a compound assignment, such as
+//the + of +=
+func applyCompound(operator string, current, val object.Object) object.Object {
+	return evalInfixExpression(strings.TrimSuffix(operator, "="), current, val)
+}
+
+//evalIndexAssignment stores val at index in an array or a hash. Unlike a
+//read, a write outside an array is an error.
+func evalIndexAssignment(left, index, val object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return newKindError(object.TYPE_ERROR, "index operator not supported: %s[%s]", left.Type(), index.Type())
+		}
+		if idx.Value < 0 || idx.Value >= int64(len(left.Elements)) {
+			return newKindError(object.INDEX_ERROR, "index out of range: %d with length %d", idx.Value, len(left.Elements))
+		}
+		left.Elements[idx.Value] = val
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newKindError(object.TYPE_ERROR, "unusable as hash key: %s", index.Type())
+		}
+		left.Set(key.HashKey(), object.HashPair{Key: index, Value: val})
+	default:
+		return newKindError(object.TYPE_ERROR, "index assignment not supported: %s", left.Type())
+	}
+	return val
 }
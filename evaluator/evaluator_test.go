@@ -1,9 +1,15 @@
 package evaluator
 
 import (
+	"OSPLang/ast"
 	"OSPLang/lexer"
 	"OSPLang/object"
 	"OSPLang/parser"
+	"OSPLang/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -299,6 +305,36 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestValuelessBlocks(t *testing.T) {
+	empty := "let f = fn() {}; "
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{empty + "f()", "null"},
+		{empty + "[f()]", "[null]"},
+		{empty + "f()[0]", "index operator not supported: NULL[INTEGER]"},
+		{empty + `{"a": 1}[f()]`, "unusable as hash key: NULL"},
+		{empty + "let a = [1]; a[0] = f(); a", "[null]"},
+		{"let g = fn() { let x = 1 }; [g()]", "[null]"},
+		{"[if (true) {}]", "[null]"},
+		{"[try {} finally {}]", "[null]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+			}
+			continue
+		}
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong value for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
 func TestStringLiteral(t *testing.T) {
 	input := `"Hello World!"`
 	evaluated := testEval(input)
@@ -334,6 +370,8 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("hello world")`, 11},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`len([1, 2, 3])`, 3},
+		{`len([])`, 0},
 	}
 
 	for _, tt := range tests {
@@ -381,11 +419,11 @@ func TestHashLiterals(t *testing.T) {
 		TRUE.HashKey():                             5,
 		FALSE.HashKey():                            6,
 	}
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
 	}
 	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+		pair, ok := result.Get(expectedKey)
 		if !ok {
 			t.Errorf("no pair for given key in Pairs")
 		}
@@ -437,3 +475,1215 @@ func TestHashIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5 + true;", "ERROR: 1:1: type mismatch: INTEGER + BOOLEAN"},
+		{"let x = 1;\nlet y = x + foo;", "ERROR: 2:13: identifier not found: foo"},
+		{"let f = fn() {\n  -true\n};\nf()", "ERROR: 2:3: unknown operator: -BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%v", tt.expected, evaluated)
+		}
+	}
+}
+
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.5", 3.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2},
+		{"7 / 2.0", 3.5},
+		{"(1 + 2 + 3) / 4.0", 1.5},
+		{"1e3 - 1", 999},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testFloatObject(t, evaluated, tt.expected)
+	}
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g",
+			result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func TestMixedNumberComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1 == 1.0", true},
+		{"1.0 != 1", false},
+		{"0.5 < 1", true},
+		{"2 > 2.5", false},
+		{"7 / 2 == 3", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestDivisionByZero(t *testing.T) {
+	evaluated := testEval("1 / 0")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object return. got=%T(%v)", evaluated, evaluated)
+	}
+	if errObj.Message != "division by zero" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+
+	if got := testEval("1 / 0.0").Inspect(); got != "+Inf" {
+		t.Errorf("float division by zero wrong. got=%q", got)
+	}
+}
+
+func TestIntegerLiteralForms(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xFF", 255},
+		{"0o17", 15},
+		{"0b1010", 10},
+		{"1_000_000", 1000000},
+		{"0x_10 + 0b1", 17},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestComparisonAndLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"2 >= 3", false},
+		{"2.5 >= 2", true},
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 && 0", true},
+		{"false && undefinedName", false},
+		{"true || undefinedName", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestLogicalShortCircuit(t *testing.T) {
+	input := `
+	let boom = fn() { 1 + true };
+	let f = false && boom();
+	let t = true || boom();
+	true && boom();
+	`
+	errObj, ok := testEval(input).(*object.Error)
+	if !ok {
+		t.Fatalf("right side was not evaluated when needed")
+	}
+	if errObj.Message != "type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestStringInterpolation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let name = "Ada"; "Hello ${name}!"`, "Hello Ada!"},
+		{`"${1 + 2} ${2.5} ${true} ${if (false) { 1 }}"`, "3 2.5 true null"},
+		{`let f = fn(x) { x * 2 }; "${f(2)}${"-"}${f(3)}"`, "4-6"},
+		{`"outer ${"inner ${1}"}"`, "outer inner 1"},
+		{`"${{"a": 1}}"`, "{a: 1}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+
+	errObj, ok := testEval(`"a ${1 + true} b"`).(*object.Error)
+	if !ok || errObj.Message != "type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("error in embedded expression not returned. got=%v", errObj)
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	if len(result.Elements) != 3 {
+		t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
+	}
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][2]", 3},
+		{"let i = 0; [1][i];", 1},
+		{"[1, 2, 3][1 + 1];", 3},
+		{"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+		{"[[1, 2], [3]][0][1]", 2},
+		{"[1, 2, 3][3]", nil},
+		{"[1, 2, 3][-1]", nil},
+		{`[1, 2]["a"]`, "index operator not supported: ARRAY[STRING]"},
+		{`5[0]`, "index operator not supported: INTEGER[INTEGER]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"while (false) { 1 }", nil},
+		{"let f = fn() { while (true) { return 5; } }; f()", 5},
+		{"let f = fn() { while (true) { break; } 7 }; f()", 7},
+		{"let f = fn(xs, t) { for (i, x in xs) { if (x == t) { return i; } } -1 }; f([4, 5, 6], 6)", 2},
+		{"let f = fn(xs, t) { for (i, x in xs) { if (x == t) { return i; } } -1 }; f([], 6)", -1},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x < 3) { continue; } return x; } }; f()", 3},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { break; } return x; } }; f()", 1},
+		{`let f = fn() { for (i, c in "éé!") { if (len(c) == 1) { return i; } } }; f()`, 2},
+		{`let f = fn() { for (c in "héllo") { if (len(c) > 1) { return len(c); } } }; f()`, 2},
+		{`let f = fn() { for (k, v in {"a": 10}) { return v; } }; f()`, 10},
+		{`let f = fn() { for (pair in {"a": 10}) { return pair[1] + len(pair[0]); } }; f()`, 11},
+		{`let f = fn() { for (x in [[1, 2]]) { for (y in x) { break; } return 9; } }; f()`, 9},
+		// break, continue and return used as values still reach their loop
+		// or function
+		{"let n = 0; while (n < 3) { n += 1; let x = if (true) { break } } n", 1},
+		{"let n = 0; while (n < 3) { n += 1; [if (true) { break }] } n", 1},
+		{`let n = 0; while (n < 3) { n += 1; {"k": if (true) { break }} } n`, 1},
+		{"let n = 0; while (n < 3) { n += 1; len(if (true) { break }) } n", 1},
+		{"let n = 0; while (n < 3) { n += 1; 1 + if (true) { break } } n", 1},
+		{"let s = 0; for (x in [1, 2, 3]) { let y = if (x == 2) { continue } else { x }; s += y } s", 4},
+		{"let f = fn() { let x = if (true) { return 5 }; 6 }; f()", 5},
+		{"let f = fn() { [1, if (true) { return 5 }]; 6 }; f()", 5},
+		{"let x = 0; while (x < 3) { x = x + 1 }; x", 3},
+		{"for (i in [1]) { }; 1", 1},
+		{"for (x in 5) { x }", "cannot iterate over INTEGER"},
+		{"let f = fn() {}; for (x in f()) {}", "cannot iterate over NULL"},
+		{"for (x in [1]) { x + true }", "type mismatch: INTEGER + BOOLEAN"},
+		{"while (1 + true) { 1 }", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x = x + 4", 5},
+		{"let x = 1; let y = 1; x = y = 3; x + y", 6},
+		{"let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x", 6},
+		{"let counter = fn() { let n = 0; fn() { n += 1 } }; let c = counter(); c(); c(); c()", 3},
+		{"let x = 1; let f = fn() { let x = 5; x = 6; }; f(); x", 1},
+		{"let sum = 0; for (x in [1, 2, 3]) { sum += x; } sum", 6},
+		{"let i = 0; while (i < 10) { i += 1; if (i == 5) { break; } } i", 5},
+		{"let a = [1, 2, 3]; a[1] = 20; a[1] + a[2]", 23},
+		{"let a = [1, 2, 3]; let b = a; b[0] += 9; a[0]", 10},
+		{`let h = {"a": 1}; h["b"] = 2; h["a"] += 40; h["a"] + h["b"]`, 43},
+		{"y = 1", "assignment to undeclared identifier: y"},
+		{"let f = fn() { z = 1 }; f()", "assignment to undeclared identifier: z"},
+		{"let x = true; x += 1", "type mismatch: BOOLEAN + INTEGER"},
+		{"let f = fn() {}; let x = 1; x += f()", "type mismatch: INTEGER + NULL"},
+		{"let f = fn() {}; let a = [f()]; a[0] *= 2", "type mismatch: NULL * INTEGER"},
+		{"let a = [1]; a[1] = 2", "index out of range: 1 with length 1"},
+		{`let a = [1]; a["0"] = 2`, "index operator not supported: ARRAY[STRING]"},
+		{`let h = {}; h[fn() {}] = 1`, "unusable as hash key: FUNCTION"},
+		{`let s = "abc"; s[0] = "x"`, "index assignment not supported: STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestHashInsertionOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"z": 1, "a": 2, 10: 3, true: 4}`, "{z: 1, a: 2, 10: 3, true: 4}"},
+		{`let h = {"b": 1, "a": 2}; h["c"] = 3; h["b"] = 4; h`, "{b: 4, a: 2, c: 3}"},
+		{`let n = 0; {(n += 1): "x", (n *= 10): "y"}`, "{1: x, 10: y}"},
+		{`let ks = ""; for (k, v in {"q": 1, "w": 2, "e": 3}) { ks = ks + k; } ks`, "qwe"},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 10; i++ {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Fatalf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(5)`, `5`},
+		{`quote(5 + 8)`, `(5 + 8)`},
+		{`quote(foobar)`, `foobar`},
+		{`quote(foobar + barfoo)`, `(foobar + barfoo)`},
+	}
+
+	for _, tt := range tests {
+		testQuoteObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestQuoteUnquote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(unquote(4))`, `4`},
+		{`quote(unquote(4 + 4))`, `8`},
+		{`quote(8 + unquote(4 + 4))`, `(8 + 8)`},
+		{`let foobar = 8; quote(unquote(foobar))`, `8`},
+		{`quote(unquote(true))`, `true`},
+		{`quote(unquote(true == false))`, `false`},
+		{`quote(unquote(quote(4 + 4)))`, `(4 + 4)`},
+		{`let q = quote(4 + 4); quote(unquote(4 + 4) + unquote(q))`, `(8 + (4 + 4))`},
+	}
+
+	for _, tt := range tests {
+		testQuoteObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func testQuoteObject(t *testing.T, obj object.Object, expected string) bool {
+	quote, ok := obj.(*object.Quote)
+	if !ok {
+		t.Errorf("expected *object.Quote. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if quote.Node == nil {
+		t.Errorf("quote.Node is nil")
+		return false
+	}
+	if quote.Node.String() != expected {
+		t.Errorf("not equal. got=%q, want=%q", quote.Node.String(), expected)
+		return false
+	}
+	return true
+}
+
+func testParseProgram(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, errs)
+	}
+	return program
+}
+
+func TestDefineMacros(t *testing.T) {
+	input := `
+	let number = 1;
+	let function = fn(x, y) { x + y };
+	let mymacro = macro(x, y) { x + y; };
+	`
+
+	env := object.NewEnvironment()
+	program := testParseProgram(t, input)
+	DefineMacros(program, env)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("wrong number of statements. got=%d", len(program.Statements))
+	}
+	if _, ok := env.Get("number"); ok {
+		t.Fatalf("number should not be defined")
+	}
+	if _, ok := env.Get("function"); ok {
+		t.Fatalf("function should not be defined")
+	}
+
+	obj, ok := env.Get("mymacro")
+	if !ok {
+		t.Fatalf("macro not in environment")
+	}
+	macro, ok := obj.(*object.Macro)
+	if !ok {
+		t.Fatalf("object is not Macro. got=%T (%+v)", obj, obj)
+	}
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("wrong number of macro parameters. got=%d", len(macro.Parameters))
+	}
+	if macro.Body.String() != "(x + y)" {
+		t.Fatalf("body is not %q. got=%q", "(x + y)", macro.Body.String())
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`let infixExpression = macro() { quote(1 + 2); }; infixExpression();`,
+			`(1 + 2)`,
+		},
+		{
+			`let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); }; reverse(2 + 2, 10 - 5);`,
+			`(10 - 5) - (2 + 2)`,
+		},
+		{
+			`let unless = macro(cond, cons, alt) {
+				quote(if (!(unquote(cond))) { unquote(cons); } else { unquote(alt); });
+			};
+			unless(10 > 5, puts("not greater"), puts("greater"));`,
+			`if (!(10 > 5)) { puts("not greater") } else { puts("greater") }`,
+		},
+		{
+			`let twice = macro(x) { quote(unquote(x) + unquote(x)); }; twice(1) * twice(a);`,
+			`((1 + 1) * (a + a))`,
+		},
+	}
+
+	for _, tt := range tests {
+		expected := testParseProgram(t, tt.expected)
+		program := testParseProgram(t, tt.input)
+
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		source := program.String()
+		expanded, err := ExpandMacros(program, env)
+		if err != nil {
+			t.Fatalf("ExpandMacros(%q) failed: %v", tt.input, err)
+		}
+		if expanded.String() != expected.String() {
+			t.Errorf("not equal. want=%q, got=%q", expected.String(), expanded.String())
+		}
+		if program.String() != source {
+			t.Errorf("program was changed. got=%q, was=%q", program.String(), source)
+		}
+	}
+}
+
+func TestMacroHygiene(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		// the macro's tmp must not capture the caller's
+		{`let add = macro(a) { quote(fn(tmp) { unquote(a) + tmp }(1)) };
+		let tmp = 10; add(tmp)`, 11},
+		{`let double = macro(x) { quote(fn() { let t = unquote(x); t * 2 }()) };
+		let t = 3; double(t) + double(t + 1)`, 14},
+		{`let m = macro(x) { quote(fn() { let [a, b] = [1, 2]; a + unquote(x) }()) };
+		let a = 10; m(a)`, 11},
+		{`let plus = macro(xs, y) { quote(match (unquote(xs)) { [x, ...rest] => x + unquote(y) }) };
+		let x = 10; plus([1, 2], x)`, 11},
+		{`let sum = macro(xs) { quote(fn() { let s = 0; for (x in unquote(xs)) { s += x; } s }()) };
+		let s = 5; let x = 1; sum([s, x, 2]) + s`, 13},
+		{`let rescue = macro(x) { quote(try { throw "no" } catch (e) { unquote(x) }) };
+		let e = 4; rescue(e + 1)`, 5},
+		{`let boxed = macro(v) { quote(fn() { struct Box { v } Box(unquote(v)).v }()) };
+		let Box = 5; boxed(Box) + Box`, 10},
+		// made-up names must not clash with the program's own
+		{`let double = macro(x) { quote(fn() { let t = unquote(x); t * 2 }()) };
+		let t_1 = 3; let t_2 = 4; double(t_1 + t_2) + t_1`, 17},
+		// code a macro returns is expanded in turn
+		{`let twice = macro(x) { quote(unquote(x) + unquote(x)) };
+		let quad = macro(x) { quote(twice(twice(unquote(x)))) }; quad(3)`, 12},
+		{`let twice = macro(x) { quote(fn() { let t = unquote(x); t + t }()) };
+		let quad = macro(x) { quote(fn() { let t = twice(unquote(x)); twice(t) }()) };
+		let t = 5; quad(t)`, 20},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(t, tt.input)
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		expanded, err := ExpandMacros(program, env)
+		if err != nil {
+			t.Fatalf("ExpandMacros(%q) failed: %v", tt.input, err)
+		}
+		testIntegerObject(t, Eval(expanded, env), tt.expected)
+	}
+}
+
+func TestMacroRenamesReadBack(t *testing.T) {
+	input := `let double = macro(x) { quote(fn() { let t = unquote(x); t * 2 }()) };
+	let t = 3; double(t) + double(t + 1)`
+
+	var first string
+	for i := 0; i < 2; i++ {
+		program := testParseProgram(t, input)
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		expanded, err := ExpandMacros(program, env)
+		if err != nil {
+			t.Fatalf("ExpandMacros(%q) failed: %v", input, err)
+		}
+		ast.Modify(expanded, func(node ast.Node) ast.Node {
+			if ident, ok := node.(*ast.Identifier); ok {
+				l := lexer.New(ident.Value)
+				tok := l.NextToken()
+				if tok.Type != token.IDENT || tok.Literal != ident.Value || l.NextToken().Type != token.EOF {
+					t.Errorf("name %q does not read back as an identifier", ident.Value)
+				}
+			}
+			return node
+		})
+		if i == 0 {
+			first = expanded.String()
+		} else if expanded.String() != first {
+			t.Errorf("expansion depends on earlier ones. got=%q, was=%q", expanded.String(), first)
+		}
+	}
+}
+
+func TestMacroRenamesAvoidBoundNames(t *testing.T) {
+	input := `let double = macro(x) { quote(fn() { let t = unquote(x); t * 2 }()) };
+	double(3)`
+
+	// t_1 was bound by an earlier chunk, as in the REPL
+	env := object.NewEnvironment()
+	env.Set("t_1", &object.Integer{Value: 100})
+	macroEnv := object.NewEnclosedEnvironment(env)
+
+	program := testParseProgram(t, input)
+	DefineMacros(program, macroEnv)
+	expanded, err := ExpandMacros(program, macroEnv)
+	if err != nil {
+		t.Fatalf("ExpandMacros(%q) failed: %v", input, err)
+	}
+	if strings.Contains(expanded.String(), "t_1") {
+		t.Errorf("renamed to a bound name. got=%q", expanded.String())
+	}
+	testIntegerObject(t, Eval(expanded, env), 6)
+}
+
+func TestMacroErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let m = macro(x) { quote(x) }; m(1, 2)`, "wrong number of arguments to macro m. got=2, want=1"},
+		{`let m = macro() { 1 }; m()`, "macro m returned INTEGER, not a quote"},
+		{`let m = macro(x) { x }; m(x: 1)`, "macro m takes no named arguments"},
+		{`let m = macro() { 1 + true }; m()`, "in macro m: type mismatch: INTEGER + BOOLEAN"},
+		{`let m = macro(x) { quote(unquote(fn() { 1 })) }; m(1)`, "in macro m: cannot unquote FUNCTION"},
+		{`let m = macro() { quote(m()) }; m()`, "macros still expand after 100 rounds"},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(t, tt.input)
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		_, err := ExpandMacros(program, env)
+		if err == nil {
+			t.Errorf("no error for %q", tt.input)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+
+	evaluated := testEval(`let f = fn() { macro(x) { x } }; f()`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "macros can only be defined by a top-level let" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`match (3) { 1 => 10, 3 => 30, _ => 0 }`, 30},
+		{`match (7) { 1 => 10, _ => 0 }`, 0},
+		{`match (2.0) { 2 => 1, _ => 0 }`, 1},
+		{`match (-1) { -1 => 1, _ => 0 }`, 1},
+		{`match ("b" + "c") { "a" => 1, "bc" => 2, _ => 0 }`, 2},
+		{`match (1 > 2) { true => 1, false => 2 }`, 2},
+		{`match (5) { n => n * 2 }`, 10},
+		{`match (5) { n if n < 0 => -1, n if n > 0 => 1, _ => 0 }`, 1},
+		{`match ([1, 2]) { [] => 0, [a] => a, [a, b] => a + b }`, 3},
+		{`match ([1, 2, 3]) { [a, b] => 0, [a, ...rest] => len(rest) }`, 2},
+		{`match ([1]) { [a, ...rest] => len(rest) }`, 0},
+		{`match ([[1, 2], 3]) { [[a, _], b] => a + b }`, 4},
+		{`match ({"name": "x", "age": 3}) { {name, age: years} => years }`, 3},
+		{`match ({"k": 1}) { {"k": 2} => 0, {"k": 1} => 1 }`, 1},
+		{`match ({1: [5]}) { {1: [n]} => n }`, 5},
+		{`match ({"a": 1}) { {b} => 0, {a} => a }`, 1},
+		{`match ("x") { [a] => 0, {a} => 1, _ => 2 }`, 2},
+		{`let n = 1; match (2) { n => n }; n`, 1},
+		{`let f = fn(x) { match (x) { 0 => 0, n => n + f(n - 1) } }; f(10)`, 55},
+		{`match (3) { 1 => 10, 2 => 20 }`, "match is not exhaustive: no arm matches 3"},
+		{`match ([1, 2]) { [a] if a > 0 => a }`, "match is not exhaustive: no arm matches [1, 2]"},
+		{`match (1) { n if n + true => 1 }`, "type mismatch: INTEGER + BOOLEAN"},
+		{`match (undefined) { _ => 1 }`, "identifier not found: undefined"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestDestructuringLet(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let [a, b] = [1, 2]; a * 10 + b`, 12},
+		{`let [a, b, ...rest] = [1, 2, 3, 4]; len(rest) * 100 + rest[1]`, 204},
+		{`let [a, ...rest] = [1]; len(rest)`, 0},
+		{`let [_, [x, y]] = [0, [3, 4]]; x + y`, 7},
+		{`let f = fn() { [1, [2, 3]] }; let [a, [b, c]] = f(); a + b + c`, 6},
+		{`let {name, age: years} = {"name": "x", "age": 30}; years`, 30},
+		{`let {"a": [first, ..._], 2: two} = {2: 20, "a": [10, 0]}; first + two`, 30},
+		{`let {a} = {"a": 1, "b": 2}; a`, 1},
+		{`let [a, b] = [1]`, "cannot destructure: array of length 1 does not match [a, b]"},
+		{`let [a] = [1, 2]`, "cannot destructure: array of length 2 does not match [a]"},
+		{`let [a, [b]] = [1, 2]`, "cannot destructure: cannot match INTEGER against array pattern [b]"},
+		{`let {name} = {"age": 3}`, "cannot destructure: hash has no key name"},
+		{`let {name} = [1]`, "cannot destructure: cannot match ARRAY against hash pattern {name: name}"},
+		{`let [1, x] = [2, 3]`, "cannot destructure: 2 does not match 1"},
+		{`let [a] = b`, "identifier not found: b"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+
+	// a failed destructuring binds none of its names
+	env := object.NewEnvironment()
+	Eval(testParseProgram(t, `let [a, b, 3] = [1, 2, 4]`), env)
+	if _, ok := env.Get("a"); ok {
+		t.Errorf("a was bound by a failed destructuring")
+	}
+}
+
+func TestFunctionArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let f = fn(x, y = 10) { x + y }; f(1)`, 11},
+		{`let f = fn(x, y = 10) { x + y }; f(1, 2)`, 3},
+		{`let f = fn(x, y = x * 10) { x + y }; f(2)`, 22},
+		{`let n = 1; let f = fn(x = n) { x }; n = 5; f()`, 5},
+		{`let f = fn(first, ...rest) { len(rest) }; f(1)`, 0},
+		{`let f = fn(first, ...rest) { rest[1] }; f(1, 2, 3)`, 3},
+		{`let f = fn(...all) { len(all) }; f(1, 2, 3)`, 3},
+		{`let f = fn(x, y) { x - y }; f(y: 1, x: 10)`, 9},
+		{`let f = fn(x, y = 5, z = 7) { x + y * z }; f(1, z: 2)`, 11},
+		{`let f = fn(x, ...r) { x + len(r) }; f(x: 4)`, 4},
+		{`let f = fn(x, y) { x }; f(1)`, "wrong number of arguments. got=1, want=2"},
+		{`let f = fn(x) { x }; f(1, 2)`, "wrong number of arguments. got=2, want=1"},
+		{`let f = fn(x, y = 1) { x }; f()`, "wrong number of arguments. got=0, want=1 to 2"},
+		{`let f = fn(x, y = 1) { x }; f(1, 2, 3)`, "wrong number of arguments. got=3, want=1 to 2"},
+		{`let f = fn(x, ...r) { x }; f()`, "wrong number of arguments. got=0, want=at least 1"},
+		{`let f = fn(x) { x }; f(z: 1)`, "unexpected named argument z"},
+		{`let f = fn(x, y) { x }; f(1, x: 2)`, "argument x given more than once"},
+		{`let f = fn(x) { x }; f(x: 1, x: 2)`, "argument x given more than once"},
+		{`let f = fn(x, y) { x }; f(y: 2)`, "missing argument for parameter x"},
+		{`let f = fn(x = y) { x }; f()`, "identifier not found: y"},
+		{`len(x: "a")`, "builtin functions take no named arguments"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestPipeExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let double = fn(x) { x * 2 }; 3 |> double`, 6},
+		{`let add = fn(x, y) { x + y }; 3 |> add(4)`, 7},
+		{`let sub = fn(x, y) { x - y }; 10 |> sub(y: 3)`, 7},
+		{`let inc = fn(x, n = 1) { x + n }; 1 |> inc |> inc(10) |> inc`, 13},
+		{`1 + 2 |> fn(x) { x * 10 }`, 30},
+		{`"abc" |> len`, 3},
+		{`2 |> [1].push() |> len`, 2},
+		{`1 |> 2`, "not a function: INTEGER"},
+		{`1 |> nope`, "identifier not found: nope"},
+		{`let f = fn(x) { x }; 1 |> f(2)`, "wrong number of arguments. got=2, want=1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestMethodCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`[1, 2, 3].len()`, 3},
+		{`"abc".len()`, 3},
+		{`let sq = fn(x) { x * x }; 3.sq()`, 9},
+		{`let add = fn(x, y) { x + y }; 3.add(y: 4)`, 7},
+		{`[1, 2, 3].map(fn(x) { x * 2 })[2]`, 6},
+		{`[1, 2, 3, 4].filter(fn(x) { x > 2 }).len()`, 2},
+		{`[1, 2].push(3)[2]`, 3},
+		{`let a = [1]; let b = a.push(2); a.len() * 10 + b.len()`, 12},
+		{`"a,b,c".split(",").len()`, 3},
+		{`" hi ".trim().len()`, 2},
+		{`{"a": 1, "b": 2}.values()[1]`, 2},
+		{`if ({"a": 1}.has("a")) { 1 } else { 0 }`, 1},
+		{`if ("hello".contains("ell")) { 1 } else { 0 }`, 1},
+		{`5.nope()`, "undefined method nope for INTEGER"},
+		{`"a".upper(1)`, "wrong number of arguments to upper. got=1, want=0"},
+		{`"a".split(1)`, "argument to split must be STRING, got INTEGER"},
+		{`"a".upper(x: 1)`, "method upper takes no named arguments"},
+		{`[1].map(fn(x) { x + true })`, "type mismatch: INTEGER + BOOLEAN"},
+		{`{}.has([1])`, "unusable as hash key: ARRAY"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+
+	stringTests := []struct {
+		input    string
+		expected string
+	}{
+		{`"Hi".upper() + "Hi".lower()`, "HIhi"},
+		{`[1, "a", true].join("-")`, "1-a-true"},
+		{`{"b": 1, "a": 2}.keys().join("")`, "ba"},
+	}
+	for _, tt := range stringTests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. got=%q, want=%q", str.Value, tt.expected)
+		}
+	}
+}
+
+//testEvalFiles writes files into a temporary directory and evaluates the
+//one named main there, with a Loader that searches searchPaths, given
+//relative to the directory
+func testEvalFiles(t *testing.T, files map[string]string, main string, searchPaths ...string) (object.Object, *object.Environment, string) {
+	t.Helper()
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range files {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, path := range searchPaths {
+		searchPaths[i] = filepath.Join(dir, path)
+	}
+
+	filename := filepath.Join(dir, main)
+	p := parser.New(lexer.NewFile(filename, strings.NewReader(files[main])))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %q", p.Errors())
+	}
+	loader := NewLoader(searchPaths...)
+	loader.Main(filename)
+	env := object.NewEnvironment()
+	env.SetImporter(loader)
+	return Eval(program, env), env, dir
+}
+
+func TestImports(t *testing.T) {
+	tests := []struct {
+		files    map[string]string
+		expected int64
+	}{
+		{map[string]string{
+			"main.osp": `import "math.osp" as math; math.square(math.two)`,
+			"math.osp": `let helper = 2; export let two = helper; export let square = fn(x) { x * x };`,
+		}, 4},
+		{map[string]string{
+			"main.osp":  `import "lib/a.osp" as a; a.x`,
+			"lib/a.osp": `import "b.osp" as b; export let x = b.y + 1;`,
+			"lib/b.osp": `export let y = 41;`,
+			"b.osp":     `export let y = 0;`,
+		}, 42},
+		{map[string]string{
+			"main.osp": `import "m.osp" as m; let s = m.sum; s + m.first`,
+			"m.osp":    `let xs = [1, 2]; export let [first, second] = xs; export let {sum} = {"sum": first + second};`,
+		}, 4},
+		{map[string]string{
+			"main.osp": `import "m.osp" as m; m.add(1, b: 2) + (3 |> m.add(4))`,
+			"m.osp":    `export let add = fn(a, b) { a + b };`,
+		}, 10},
+	}
+
+	for _, tt := range tests {
+		evaluated, _, _ := testEvalFiles(t, tt.files, "main.osp")
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestImportSearchPaths(t *testing.T) {
+	files := map[string]string{
+		"src/main.osp": `import "util.osp" as util; util.x`,
+		"one/util.osp": `export let x = 1;`,
+		"two/util.osp": `export let x = 2;`,
+	}
+	evaluated, _, _ := testEvalFiles(t, files, "src/main.osp", "one", "two")
+	testIntegerObject(t, evaluated, 1)
+
+	files["src/util.osp"] = `export let x = 3;`
+	evaluated, _, _ = testEvalFiles(t, files, "src/main.osp", "one", "two")
+	testIntegerObject(t, evaluated, 3)
+}
+
+func TestImportsAreCached(t *testing.T) {
+	files := map[string]string{
+		"main.osp":   `import "a.osp" as a; import "b.osp" as b; import "./shared.osp" as s1; import "shared.osp" as s2;`,
+		"a.osp":      `import "shared.osp" as s; export let shared = s;`,
+		"b.osp":      `import "shared.osp" as s; export let shared = s;`,
+		"shared.osp": `export let xs = [];`,
+	}
+	_, env, dir := testEvalFiles(t, files, "main.osp")
+
+	s1, _ := env.Get("s1")
+	s2, _ := env.Get("s2")
+	a, _ := env.Get("a")
+	b, _ := env.Get("b")
+	module, ok := s1.(*object.Module)
+	if !ok {
+		t.Fatalf("s1 is not Module. got=%T (%+v)", s1, s1)
+	}
+	if module.Name != filepath.Join(dir, "shared.osp") {
+		t.Errorf("module has wrong name. got=%q", module.Name)
+	}
+	if s2 != s1 || a.(*object.Module).Exports["shared"] != s1 || b.(*object.Module).Exports["shared"] != s1 {
+		t.Errorf("shared.osp was loaded more than once")
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		files    map[string]string
+		expected string // with DIR standing for the temporary directory
+	}{
+		{map[string]string{
+			"main.osp": `import "nope.osp" as nope;`,
+		}, `cannot find module "nope.osp"`},
+		{map[string]string{
+			"main.osp": `import "m.osp" as m; m.y`,
+			"m.osp":    `let y = 1; export let x = y;`,
+		}, "module DIR/m.osp does not export y"},
+		{map[string]string{
+			"main.osp": `import "m.osp" as m; m.f()`,
+			"m.osp":    `export let x = 1;`,
+		}, "module DIR/m.osp does not export f"},
+		{map[string]string{
+			"main.osp": `let x = 1; x.y`,
+		}, "INTEGER has no member y"},
+		{map[string]string{
+			"main.osp": `import "a.osp" as a;`,
+			"a.osp":    `import "b.osp" as b;`,
+			"b.osp":    `import "a.osp" as a;`,
+		}, "import cycle: DIR/a.osp -> DIR/b.osp -> DIR/a.osp"},
+		{map[string]string{
+			"main.osp": `import "a.osp" as a;`,
+			"a.osp":    `import "main.osp" as main;`,
+		}, "import cycle: DIR/main.osp -> DIR/a.osp -> DIR/main.osp"},
+		{map[string]string{
+			"main.osp": `import "m.osp" as m;`,
+			"m.osp":    `export let x = 1 + true;`,
+		}, "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		evaluated, _, dir := testEvalFiles(t, tt.files, "main.osp")
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		expected := strings.Replace(tt.expected, "DIR", dir, -1)
+		if errObj.Message != expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+		}
+	}
+
+	evaluated := testEval(`import "m.osp" as m;`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "imports are not available here" {
+		t.Errorf("wrong result without an importer. got=%T(%+v)", evaluated, evaluated)
+	}
+}
+
+func TestTryCatchFinally(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 1 } catch (e) { 2 }`, 1},
+		{`try { 1 + true; 1 } catch (e) { 2 }`, 2},
+		{`try { throw "boom"; 1 } catch { 2 }`, 2},
+		{`try { nope } catch (e) { len(e.message) }`, 26},
+		{`let f = fn() { g() }; try { f() } catch (e) { 3 }`, 3},
+		{`try { len(1) } catch (e) { 4 }`, 4},
+		{`try { "a".upper(1) } catch (e) { 5 }`, 5},
+		{`try { try { 1 / 0 } catch (e) { throw e } } catch (e) { 6 }`, 6},
+		{`let x = 0; try { x = 1 } finally { x = x + 10 }; x`, 11},
+		{`let x = 0; try { throw "a" } catch { x = 1 } finally { x = x + 10 }; x`, 11},
+		{`let x = 0; try { try { throw "a" } finally { x = 7 } } catch { x * 2 }`, 14},
+		{`let f = fn() { try { return 1 } finally { 2 } }; f()`, 1},
+		{`let f = fn() { try { return 1 } finally { return 2 } }; f()`, 2},
+		{`try { 1 } finally { throw "late" }`, "late"},
+		{`let n = 0; while (true) { try { break } finally { n = n + 1 } } n`, 1},
+		{`let f = fn() { try { 1 / 0 } catch (e) { return 8 } }; f()`, 8},
+		{`try { 1 } catch (e) { 2 }; e`, "identifier not found: e"},
+		{`try { throw "a" } finally { 1 }`, "a"},
+		{`try { throw "a" } catch (e) { throw "b" }`, "b"},
+		{`throw 1`, "cannot throw INTEGER"},
+		{"let f = fn() {}; error(f())", "argument 1 to `error` must be STRING, got NULL"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestErrorValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`try { 1 + true } catch (e) { e.kind }`, "TypeError"},
+		{`try { nope } catch (e) { e.kind }`, "NameError"},
+		{`try { fn(a) { a }() } catch (e) { e.kind }`, "ArgumentError"},
+		{`try { [1][1] = 2 } catch (e) { e.kind }`, "IndexError"},
+		{`try { 1 / 0 } catch (e) { e.kind }`, "ArithmeticError"},
+		{`try { match (1) { 2 => 3 } } catch (e) { e.kind }`, "MatchError"},
+		{`try { throw "boom" } catch (e) { e.kind + ": " + e.message }`, "Error: boom"},
+		{`try { throw error("bad input", "ValueError") } catch (e) { e.kind }`, "ValueError"},
+		{`let c = error("root"); try { throw error("top", "Error", c) } catch (e) { e.cause.message }`, "root"},
+		{`try { try { 1 / 0 } catch { throw error("wrapped") } } catch (e) { e.cause.message }`, "division by zero"},
+		{`try { try { throw error("a", "A", error("b", "B")) } catch (e) { throw e.cause } } catch (e) { e.kind }`, "B"},
+		{`try { try { throw error("a") } catch (e) { throw e } } catch (e) { "${e.cause}" }`, "null"},
+		{`let E = error("shared"); let f = fn() { try { 1 / 0 } catch (e) { throw E } };
+		try { f() } catch (e) { 0 }; "${E.cause}"`, "null"},
+		{`let E = error("shared"); try { try { 1 / 0 } catch { throw E } } catch (e) { e.cause.kind }`, "ArithmeticError"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, str.Value)
+		}
+	}
+}
+
+func TestThrowStoredError(t *testing.T) {
+	input := `let E = error("again");
+try { throw E } catch { 0 };
+throw E`
+
+	evaluated := testEval(input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Pos.Line != 3 {
+		t.Errorf("wrong position for second throw. want line 3, got=%s", errObj.Pos)
+	}
+	if errObj.Cause != nil {
+		t.Errorf("second throw has a cause. got=%s", errObj.Cause.Inspect())
+	}
+}
+
+func TestStructs(t *testing.T) {
+	point := `struct Point {
+		x, y
+		fn norm() { self.x * self.x + self.y * self.y }
+		fn add(other) { Point(self.x + other.x, self.y + other.y) }
+		fn scale(k = 2) { Point(self.x * k, self.y * k) }
+		fn move(dx) { self.x += dx; self }
+	}
+	`
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{point + `Point(3, 4).x`, 3},
+		{point + `Point(y: 4, x: 3).y`, 4},
+		{point + `Point(3, y: 4).norm()`, 25},
+		{point + `Point(1, 2).add(Point(3, 4)).y`, 6},
+		{point + `Point(1, 2).scale().x + Point(1, 2).scale(k: 10).y`, 22},
+		{point + `let p = Point(1, 2); p.move(5); p.x`, 6},
+		{point + `let p = Point(1, 2); p.y = 7; p.y *= 2; p.y`, 14},
+		{point + `let norm = Point(3, 4).norm; norm()`, 25},
+		{point + `let ps = [Point(1, 1), Point(2, 2)]; ps.map(fn(p) { p.norm() })[1]`, 8},
+		{point + `Point(2, 0) |> fn(p) { p.x }`, 2},
+		{point + `let x = 1; Point(3, 4).norm() + x`, 26},
+		{`struct Box { f } Box(fn(x) { x * 3 }).f(2)`, 6},
+		{`struct Node { value, next } let n = Node(1, Node(2, 0)); n.next.value`, 2},
+		{`let make = fn() { struct Local { v } Local(9) }; make().v`, 9},
+		{point + `if (Point(1, 2) == Point(1, 2)) { 1 } else { 0 }`, 1},
+		{point + `if (Point(1, 2) != Point(1, 3)) { 1 } else { 0 }`, 1},
+		{`struct S { name } if (S("a") == S("a")) { 1 } else { 0 }`, 1},
+		{`struct A { x } struct B { x } if (A(1) == B(1)) { 1 } else { 0 }`, 0},
+		{`struct N { v } if (N(N(1)) == N(N(1))) { 1 } else { 0 }`, 1},
+		{`struct P { x }; P(1).x`, 1},
+		{`let f = fn() {}; struct P { x }; let a = P(1); a.x = f(); if (a == P(2)) { 1 } else { 0 }`, 0},
+		{`let f = fn() {}; struct P { x }; if (P(f()) == P(f())) { 1 } else { 0 }`, 1},
+		{`struct N { next } let a = N(0); a.next = a; let b = N(0); b.next = b; if (a == b) { 1 } else { 0 }`, 1},
+		{`struct N { v, next } let a = N(1, 0); a.next = a; let b = N(2, 0); b.next = b; if (a != b) { 1 } else { 0 }`, 1},
+		{point + `Point(1)`, "missing value for field y of Point"},
+		{point + `Point(1, 2, 3)`, "wrong number of arguments to Point. got=3, want=2"},
+		{point + `Point(1, 2, z: 3)`, "Point has no field z"},
+		{point + `Point(1, 2, x: 3)`, "field x given more than once"},
+		{point + `Point(1, 2).z`, "Point has no field or method z"},
+		{point + `let p = Point(1, 2); p.z = 3`, "Point has no field z"},
+		{point + `Point(1, 2).nope()`, "undefined method nope for INSTANCE"},
+		{point + `Point(1, 2) + 1`, "type mismatch: INSTANCE + INTEGER"},
+		{`let h = {}; h.x = 1`, "cannot assign to member x of HASH"},
+		{`struct P { x, fn get() { x } } P(1).get()`, "identifier not found: x"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestStructInspect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`struct Point { x, y } Point(1, "a")`, "Point{x: 1, y: a}"},
+		{`struct Point { x, y } Point(y: [1], x: Point(2, 3))`, "Point{x: Point{x: 2, y: 3}, y: [1]}"},
+		{`struct Point { x, y, fn norm() { 0 } } Point`, "struct Point { x, y }"},
+		{`struct Unit {} Unit()`, "Unit{}"},
+		{`struct N { next } let a = N(0); a.next = a; a`, "N{next: N{...}}"},
+		{`struct N { next } let a = N(0); a.next = [a, a]; a`, "N{next: [N{...}, N{...}]}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong Inspect for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
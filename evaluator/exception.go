@@ -0,0 +1,82 @@
+package evaluator
+
+import (
+	"OSPLang/ast"
+	"OSPLang/object"
+)
+
+//evalThrowStatement raises the error that a throw names. A string is
+//raised as the message of an error of the generic kind. An error value
+//is raised as a copy, so that the position and cause given to the raised
+//error do not stick to the value.
+func evalThrowStatement(ts *ast.ThrowStatement, env *object.Environment) object.Object {
+	val := Eval(ts.Value, env)
+	if isAbrupt(val) {
+		return val
+	}
+	switch val := val.(type) {
+	case *object.ErrorValue:
+		return &object.Error{Message: val.Error.Message, Kind: val.Error.Kind, Cause: val.Error.Cause}
+	case *object.String:
+		return &object.Error{Message: val.Value, Kind: object.GENERIC_ERROR}
+	default:
+		return newKindError(object.TYPE_ERROR, "cannot throw %s", typeOf(val))
+	}
+}
+
+//evalTryExpression evaluates the body of a try, and the catch if the body
+//raised an error. An error raised by the catch gets the caught one as its
+//cause unless it has one already. The finally runs last whatever
+//happened; if it raises an error, returns or leaves a loop, that wins
+//over the outcome of the body and catch.
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := Eval(te.Body, env)
+
+	if caught, ok := result.(*object.Error); ok && te.Catch != nil {
+		catchEnv := env
+		if te.Param != nil {
+			catchEnv = object.NewEnclosedEnvironment(env)
+			catchEnv.Set(te.Param.Value, &object.ErrorValue{Error: caught})
+		}
+		result = Eval(te.Catch, catchEnv)
+		if raised, ok := result.(*object.Error); ok && raised.Cause == nil && !causedBy(caught, raised) {
+			raised.Cause = caught
+		}
+	}
+
+	if te.Finally != nil {
+		if final := Eval(te.Finally, env); isAbrupt(final) {
+			return final
+		}
+	}
+	return result
+}
+
+//causedBy reports whether cause is err or one of the errors that led to
+//it. Errors are compared by message, kind and cause, since a rethrown
+//error is a copy of the caught one.
+func causedBy(err, cause *object.Error) bool {
+	for ; err != nil; err = err.Cause {
+		if err.Message == cause.Message && err.Kind == cause.Kind && err.Cause == cause.Cause {
+			return true
+		}
+	}
+	return false
+}
+
+//errorMember returns the member name of an error value: its message,
+//kind or cause. It returns nil for any other name.
+func errorMember(err *object.Error, name string) object.Object {
+	switch name {
+	case "message":
+		return &object.String{Value: err.Message}
+	case "kind":
+		return &object.String{Value: err.Kind}
+	case "cause":
+		if err.Cause == nil {
+			return NULL
+		}
+		return &object.ErrorValue{Error: err.Cause}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package evaluator
+
+import (
+	"OSPLang/ast"
+	"OSPLang/object"
+	"fmt"
+)
+
+//DefineMacros removes the macro definitions, let name = macro(...) { },
+//from the top level of program and binds them in env
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	statements := []ast.Statement{}
+	for _, stmt := range program.Statements {
+		if let, ok := stmt.(*ast.LetStatement); ok && let.Name != nil {
+			if lit, ok := let.Value.(*ast.MacroLiteral); ok {
+				env.Set(let.Name.Value, &object.Macro{
+					Parameters: lit.Parameters,
+					Body:       lit.Body,
+					Env:        env,
+				})
+				continue
+			}
+		}
+		statements = append(statements, stmt)
+	}
+	program.Statements = statements
+}
+
+//maxExpansions bounds how many times ExpandMacros goes over a program, so
+//that a macro whose code calls itself cannot expand forever
+const maxExpansions = 100
+
+//ExpandMacros returns a copy of program in which every call to a macro
+//bound in env is replaced by the quote the macro returns for the call's
+//unevaluated arguments. program itself is left as it is. Macro calls in
+//the code that a macro returns are expanded in turn, until none is left.
+//
+//Expansion is hygienic: names that the macro's code binds are renamed so
+//that they can neither capture nor shadow the names in the arguments.
+//The new names are not used in the program and not bound in env.
+func ExpandMacros(program *ast.Program, env *object.Environment) (*ast.Program, error) {
+	names := newNamer(program, env)
+	for i := 0; i < maxExpansions; i++ {
+		expanded, more, err := expandMacroCalls(program, env, names)
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			return expanded, nil
+		}
+		program = expanded
+	}
+	return nil, fmt.Errorf("macros still expand after %d rounds", maxExpansions)
+}
+
+//expandMacroCalls does one round of ExpandMacros, replacing the macro
+//calls in program but not those in the code they expand to. It reports
+//whether it replaced any.
+func expandMacroCalls(program *ast.Program, env *object.Environment, names *namer) (*ast.Program, bool, error) {
+	var err error
+	more := false
+
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || err != nil {
+			return node
+		}
+		ident, ok := call.Function.(*ast.Identifier)
+		if !ok {
+			return node
+		}
+		obj, ok := env.Get(ident.Value)
+		if !ok {
+			return node
+		}
+		macro, ok := obj.(*object.Macro)
+		if !ok {
+			return node
+		}
+
+		if len(call.Arguments) != len(macro.Parameters) {
+			err = fmt.Errorf("%s: wrong number of arguments to macro %s. got=%d, want=%d",
+				call.Pos(), ident.Value, len(call.Arguments), len(macro.Parameters))
+			return node
+		}
+		for _, arg := range call.Arguments {
+			if _, ok := arg.(*ast.NamedArgument); ok {
+				err = fmt.Errorf("%s: macro %s takes no named arguments", arg.Pos(), ident.Value)
+				return node
+			}
+		}
+		macroEnv := object.NewEnclosedEnvironment(macro.Env)
+		for i, param := range macro.Parameters {
+			macroEnv.Set(param.Value, &object.Quote{Node: call.Arguments[i]})
+		}
+
+		evaluated := unwrapReturnValue(Eval(macro.Body, macroEnv))
+		switch evaluated := evaluated.(type) {
+		case *object.Quote:
+			more = true
+			return hygienic(evaluated, names)
+		case *object.Error:
+			err = fmt.Errorf("%s: in macro %s: %s", evaluated.Pos, ident.Value, evaluated.Message)
+		default:
+			err = fmt.Errorf("%s: macro %s returned %s, not a quote",
+				call.Pos(), ident.Value, typeOf(evaluated))
+		}
+		return node
+	})
+
+	if err != nil {
+		return nil, false, err
+	}
+	return expanded.(*ast.Program), more, nil
+}
+
+//namer makes up the fresh names of one expansion. A made-up name is the
+//old one with _ and a number appended, so that it still reads back as an
+//identifier; it is fresh because it is neither the name of a builtin nor
+//one used in the program or in any macro's code, nor one bound in env,
+//such as a REPL's earlier definitions.
+type namer struct {
+	taken map[string]bool
+	env   *object.Environment
+	count int
+}
+
+func newNamer(program *ast.Program, env *object.Environment) *namer {
+	n := &namer{taken: map[string]bool{}, env: env}
+	for name := range builtins {
+		n.taken[name] = true
+	}
+	n.reserve(program)
+	return n
+}
+
+//reserve marks the names used in node as taken
+func (n *namer) reserve(node ast.Node) {
+	ast.Modify(node, func(node ast.Node) ast.Node {
+		if ident, ok := node.(*ast.Identifier); ok {
+			n.taken[ident.Value] = true
+		}
+		return node
+	})
+}
+
+//fresh returns a name for name that is not taken and takes it
+func (n *namer) fresh(name string) string {
+	for {
+		n.count++
+		candidate := fmt.Sprintf("%s_%d", name, n.count)
+		if _, bound := n.env.Get(candidate); !n.taken[candidate] && !bound {
+			n.taken[candidate] = true
+			return candidate
+		}
+	}
+}
+
+//hygienic returns the code of q with the names that it binds, by let, as
+//parameters, as loop variables, in patterns, by import, by catch or by
+//struct, renamed to fresh ones. The code that unquote spliced in,
+//usually the macro's arguments, keeps its names: it belongs to the call
+//site. The fresh names come from names.
+func hygienic(q *object.Quote, names *namer) ast.Node {
+	spliced := map[ast.Node]bool{}
+	for _, code := range q.Unquoted {
+		ast.Modify(code, func(node ast.Node) ast.Node {
+			spliced[node] = true
+			return node
+		})
+	}
+
+	names.reserve(q.Node)
+	renames := map[string]string{}
+	bind := func(ident *ast.Identifier) {
+		if ident == nil || spliced[ident] {
+			return
+		}
+		if _, ok := renames[ident.Value]; !ok {
+			renames[ident.Value] = names.fresh(ident.Value)
+		}
+	}
+	ast.Modify(q.Node, func(node ast.Node) ast.Node {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			bind(node.Name)
+		case *ast.FunctionLiteral:
+			for _, param := range node.Parameters {
+				bind(param)
+			}
+			bind(node.Rest)
+		case *ast.ForInStatement:
+			bind(node.Key)
+			bind(node.Value)
+		case *ast.BindingPattern:
+			bind(node.Name)
+		case *ast.ImportStatement:
+			bind(node.Alias)
+		case *ast.TryExpression:
+			bind(node.Param)
+		case *ast.StructStatement:
+			bind(node.Name)
+		}
+		return node
+	})
+	if len(renames) == 0 {
+		return q.Node
+	}
+
+	return ast.Modify(q.Node, func(node ast.Node) ast.Node {
+		ident, ok := node.(*ast.Identifier)
+		if !ok || spliced[ident] {
+			return node
+		}
+		if name, ok := renames[ident.Value]; ok {
+			return &ast.Identifier{Token: ident.Token, Value: name}
+		}
+		return node
+	})
+}
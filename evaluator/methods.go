@@ -0,0 +1,271 @@
+package evaluator
+
+import (
+	"OSPLang/ast"
+	"OSPLang/object"
+	"strings"
+)
+
+//method is a built-in method of a type: it gets the receiver of the call
+//and its arguments
+type method func(receiver object.Object, args ...object.Object) object.Object
+
+//methods holds the built-in methods of each type. It is filled in by init
+//because some methods call back into the evaluator.
+var methods map[object.ObjectType]map[string]method
+
+func init() {
+	methods = map[object.ObjectType]map[string]method{
+		object.STRING_OBJ: {
+			"upper":    stringUpper,
+			"lower":    stringLower,
+			"trim":     stringTrim,
+			"split":    stringSplit,
+			"contains": stringContains,
+		},
+		object.ARRAY_OBJ: {
+			"push":   arrayPush,
+			"map":    arrayMap,
+			"filter": arrayFilter,
+			"join":   arrayJoin,
+		},
+		object.HASH_OBJ: {
+			"keys":   hashKeys,
+			"values": hashValues,
+			"has":    hashHas,
+		},
+	}
+}
+
+//evalPipeExpression calls the right side of |> with the value of the left
+//side as its first argument
+func evalPipeExpression(pe *ast.PipeExpression, env *object.Environment) object.Object {
+	left := Eval(pe.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+
+	switch right := pe.Right.(type) {
+	case *ast.CallExpression:
+		function := Eval(right.Function, env)
+		if isAbrupt(function) {
+			return function
+		}
+		args, named, err := evalArguments(right.Arguments, env)
+		if err != nil {
+			return err
+		}
+		return applyFunction(function, append([]object.Object{left}, args...), named)
+
+	case *ast.MethodCallExpression:
+		receiver := Eval(right.Receiver, env)
+		if isAbrupt(receiver) {
+			return receiver
+		}
+		args, named, err := evalArguments(right.Arguments, env)
+		if err != nil {
+			return err
+		}
+		return callMethod(receiver, right.Method.Value, append([]object.Object{left}, args...), named, env)
+
+	default:
+		function := Eval(pe.Right, env)
+		if isAbrupt(function) {
+			return function
+		}
+		return applyFunction(function, []object.Object{left}, nil)
+	}
+}
+
+func evalMethodCallExpression(mc *ast.MethodCallExpression, env *object.Environment) object.Object {
+	receiver := Eval(mc.Receiver, env)
+	if isAbrupt(receiver) {
+		return receiver
+	}
+	args, named, err := evalArguments(mc.Arguments, env)
+	if err != nil {
+		return err
+	}
+	return callMethod(receiver, mc.Method.Value, args, named, env)
+}
+
+//callMethod calls the method name of receiver's type, or else the
+//function bound to name, with receiver as the first argument. For a
+//module it calls the function the module exports as name, and for an
+//instance its method or field name, if it has one.
+func callMethod(receiver object.Object, name string, args []object.Object, named map[string]object.Object, env *object.Environment) object.Object {
+	switch receiver := receiver.(type) {
+	case *object.Module:
+		fn, ok := receiver.Exports[name]
+		if !ok {
+			return newKindError(object.NAME_ERROR, "module %s does not export %s", receiver.Name, name)
+		}
+		return applyFunction(fn, args, named)
+	case *object.Instance:
+		if fn := instanceMember(receiver, name); fn != nil {
+			return applyFunction(fn, args, named)
+		}
+	}
+
+	if method, ok := methods[typeOf(receiver)][name]; ok {
+		if len(named) > 0 {
+			return newKindError(object.ARGUMENT_ERROR, "method %s takes no named arguments", name)
+		}
+		return method(receiver, args...)
+	}
+
+	fn, ok := env.Get(name)
+	if !ok {
+		if builtin, isBuiltin := builtins[name]; isBuiltin {
+			fn, ok = builtin, true
+		}
+	}
+	if !ok {
+		return newKindError(object.NAME_ERROR, "undefined method %s for %s", name, typeOf(receiver))
+	}
+	return applyFunction(fn, append([]object.Object{receiver}, args...), named)
+}
+
+//checkArgs returns an error unless method name got want arguments, each
+//of the given type if one is given
+func checkArgs(name string, args []object.Object, want int, types ...object.ObjectType) *object.Error {
+	if len(args) != want {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments to %s. got=%d, want=%d", name, len(args), want)
+	}
+	for i, t := range types {
+		if typeOf(args[i]) != t {
+			return newKindError(object.TYPE_ERROR, "argument to %s must be %s, got %s", name, t, typeOf(args[i]))
+		}
+	}
+	return nil
+}
+
+func stringUpper(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArgs("upper", args, 0); err != nil {
+		return err
+	}
+	return &object.String{Value: strings.ToUpper(receiver.(*object.String).Value)}
+}
+
+func stringLower(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArgs("lower", args, 0); err != nil {
+		return err
+	}
+	return &object.String{Value: strings.ToLower(receiver.(*object.String).Value)}
+}
+
+func stringTrim(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArgs("trim", args, 0); err != nil {
+		return err
+	}
+	return &object.String{Value: strings.TrimSpace(receiver.(*object.String).Value)}
+}
+
+func stringSplit(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArgs("split", args, 1, object.STRING_OBJ); err != nil {
+		return err
+	}
+	parts := strings.Split(receiver.(*object.String).Value, args[0].(*object.String).Value)
+	elements := make([]object.Object, len(parts))
+	for i, part := range parts {
+		elements[i] = &object.String{Value: part}
+	}
+	return &object.Array{Elements: elements}
+}
+
+func stringContains(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArgs("contains", args, 1, object.STRING_OBJ); err != nil {
+		return err
+	}
+	return nativeBoolToBoolanObject(strings.Contains(receiver.(*object.String).Value, args[0].(*object.String).Value))
+}
+
+//arrayPush returns a new array with the argument after the elements of
+//the receiver, which is left as it is
+func arrayPush(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArgs("push", args, 1); err != nil {
+		return err
+	}
+	array := receiver.(*object.Array)
+	elements := make([]object.Object, len(array.Elements), len(array.Elements)+1)
+	copy(elements, array.Elements)
+	return &object.Array{Elements: append(elements, args[0])}
+}
+
+func arrayMap(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArgs("map", args, 1); err != nil {
+		return err
+	}
+	array := receiver.(*object.Array)
+	elements := make([]object.Object, len(array.Elements))
+	for i, el := range array.Elements {
+		mapped := applyFunction(args[0], []object.Object{el}, nil)
+		if isAbrupt(mapped) {
+			return mapped
+		}
+		elements[i] = mapped
+	}
+	return &object.Array{Elements: elements}
+}
+
+func arrayFilter(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArgs("filter", args, 1); err != nil {
+		return err
+	}
+	elements := []object.Object{}
+	for _, el := range receiver.(*object.Array).Elements {
+		keep := applyFunction(args[0], []object.Object{el}, nil)
+		if isAbrupt(keep) {
+			return keep
+		}
+		if isTruthy(keep) {
+			elements = append(elements, el)
+		}
+	}
+	return &object.Array{Elements: elements}
+}
+
+func arrayJoin(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArgs("join", args, 1, object.STRING_OBJ); err != nil {
+		return err
+	}
+	parts := []string{}
+	for _, el := range receiver.(*object.Array).Elements {
+		parts = append(parts, inspect(el))
+	}
+	return &object.String{Value: strings.Join(parts, args[0].(*object.String).Value)}
+}
+
+func hashKeys(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArgs("keys", args, 0); err != nil {
+		return err
+	}
+	keys := []object.Object{}
+	for _, pair := range receiver.(*object.Hash).Ordered() {
+		keys = append(keys, pair.Key)
+	}
+	return &object.Array{Elements: keys}
+}
+
+func hashValues(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArgs("values", args, 0); err != nil {
+		return err
+	}
+	values := []object.Object{}
+	for _, pair := range receiver.(*object.Hash).Ordered() {
+		values = append(values, pair.Value)
+	}
+	return &object.Array{Elements: values}
+}
+
+func hashHas(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArgs("has", args, 1); err != nil {
+		return err
+	}
+	key, ok := args[0].(object.Hashable)
+	if !ok {
+		return newKindError(object.TYPE_ERROR, "unusable as hash key: %s", typeOf(args[0]))
+	}
+	_, ok = receiver.(*object.Hash).Get(key.HashKey())
+	return nativeBoolToBoolanObject(ok)
+}
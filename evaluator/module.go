@@ -0,0 +1,198 @@
+package evaluator
+
+import (
+	"OSPLang/ast"
+	"OSPLang/lexer"
+	"OSPLang/object"
+	"OSPLang/parser"
+	"OSPLang/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//Loader finds, evaluates and caches the modules of import statements. It
+//is an object.Importer: set it on an environment with SetImporter.
+//
+//A relative import path is looked for next to the importing file first,
+//then in each of SearchPaths in turn. Each module is evaluated once, in
+//an environment of its own, and cached by the canonical path of its file.
+type Loader struct {
+	SearchPaths []string
+
+	modules map[string]*object.Module
+	loading []string // canonical paths of the modules being evaluated
+}
+
+//NewLoader returns a Loader that searches the given directories
+func NewLoader(searchPaths ...string) *Loader {
+	return &Loader{SearchPaths: searchPaths, modules: map[string]*object.Module{}}
+}
+
+//Main records filename as the script being run, so that a module that
+//imports it back is reported as an import cycle
+func (l *Loader) Main(filename string) {
+	l.loading = []string{canonical(filename)}
+}
+
+//Import loads the module at path, imported at from
+func (l *Loader) Import(path string, from token.Position) object.Object {
+	file, ok := l.resolve(path, from.Filename)
+	if !ok {
+		return newKindError(object.IMPORT_ERROR, "cannot find module %q", path)
+	}
+	if module, ok := l.modules[file]; ok {
+		return module
+	}
+	for i, loading := range l.loading {
+		if loading == file {
+			chain := append(append([]string{}, l.loading[i:]...), file)
+			return newKindError(object.IMPORT_ERROR, "import cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	l.loading = append(l.loading, file)
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+
+	result := l.load(file)
+	if module, ok := result.(*object.Module); ok {
+		l.modules[file] = module
+	}
+	return result
+}
+
+//resolve returns the canonical path of the file that path names
+func (l *Loader) resolve(path, from string) (string, bool) {
+	var candidates []string
+	if filepath.IsAbs(path) {
+		candidates = []string{path}
+	} else {
+		if from != "" {
+			candidates = append(candidates, filepath.Join(filepath.Dir(from), path))
+		}
+		for _, dir := range l.SearchPaths {
+			candidates = append(candidates, filepath.Join(dir, path))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return canonical(candidate), true
+		}
+	}
+	return "", false
+}
+
+//canonical returns the absolute path of file with symbolic links
+//resolved, as far as that can be done
+func canonical(file string) string {
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(file); err == nil {
+		file = resolved
+	}
+	return file
+}
+
+//load evaluates the module in file
+func (l *Loader) load(file string) object.Object {
+	f, err := os.Open(file)
+	if err != nil {
+		return newKindError(object.IMPORT_ERROR, "cannot read module %s: %v", file, err)
+	}
+	defer f.Close()
+
+	lex := lexer.NewFile(file, f)
+	p := parser.New(lex)
+	program := p.ParseProgram()
+	if err := lex.Err(); err != nil {
+		return newKindError(object.IMPORT_ERROR, "cannot read module %s: %v", file, err)
+	}
+	if errors := p.Errors(); len(errors) != 0 {
+		return newKindError(object.IMPORT_ERROR, "cannot parse module %s: %s", file, strings.Join(errors, "; "))
+	}
+
+	macroEnv := object.NewEnvironment()
+	DefineMacros(program, macroEnv)
+	expanded, err := ExpandMacros(program, macroEnv)
+	if err != nil {
+		return newKindError(object.IMPORT_ERROR, "macro expansion failed in module %s: %v", file, err)
+	}
+
+	env := object.NewEnvironment()
+	env.SetImporter(l)
+	if result := Eval(expanded, env); isAbrupt(result) {
+		return result
+	}
+
+	module := &object.Module{Name: file, Exports: map[string]object.Object{}}
+	for _, stmt := range expanded.Statements {
+		export, ok := stmt.(*ast.ExportStatement)
+		if !ok {
+			continue
+		}
+		for _, name := range boundNames(export.Statement) {
+			if _, ok := module.Exports[name]; !ok {
+				module.Names = append(module.Names, name)
+			}
+			module.Exports[name], _ = env.Get(name)
+		}
+	}
+	return module
+}
+
+//boundNames lists the names that a let binds
+func boundNames(let *ast.LetStatement) []string {
+	if let.Pattern == nil {
+		return []string{let.Name.Value}
+	}
+	names := []string{}
+	ast.Modify(let.Pattern, func(node ast.Node) ast.Node {
+		if binding, ok := node.(*ast.BindingPattern); ok {
+			names = append(names, binding.Name.Value)
+		}
+		return node
+	})
+	return names
+}
+
+func evalImportStatement(is *ast.ImportStatement, env *object.Environment) object.Object {
+	importer := env.Importer()
+	if importer == nil {
+		return newKindError(object.IMPORT_ERROR, "imports are not available here")
+	}
+	module := importer.Import(is.Path.Value, is.Pos())
+	if isAbrupt(module) {
+		return module
+	}
+	env.Set(is.Alias.Value, module)
+	return nil
+}
+
+//evalMemberExpression reads a member of a value, such as an export of a
+//module, the message of an error or a field of an instance
+func evalMemberExpression(me *ast.MemberExpression, env *object.Environment) object.Object {
+	obj := Eval(me.Object, env)
+	if isAbrupt(obj) {
+		return obj
+	}
+	switch obj := obj.(type) {
+	case *object.Module:
+		val, ok := obj.Exports[me.Name.Value]
+		if !ok {
+			return newKindError(object.NAME_ERROR, "module %s does not export %s", obj.Name, me.Name.Value)
+		}
+		return val
+	case *object.ErrorValue:
+		if val := errorMember(obj.Error, me.Name.Value); val != nil {
+			return val
+		}
+	case *object.Instance:
+		if val := instanceMember(obj, me.Name.Value); val != nil {
+			return val
+		}
+		return newKindError(object.NAME_ERROR, "%s has no field or method %s", obj.Struct.Name, me.Name.Value)
+	}
+	return newKindError(object.TYPE_ERROR, "%s has no member %s", typeOf(obj), me.Name.Value)
+}
@@ -0,0 +1,138 @@
+package evaluator
+
+import (
+	"OSPLang/ast"
+	"OSPLang/object"
+)
+
+//evalMatchExpression evaluates the body of the first arm whose pattern
+//matches the subject and whose guard holds. Each arm binds its names in
+//an environment of its own.
+func evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(me.Subject, env)
+	if isAbrupt(subject) {
+		return subject
+	}
+
+	for _, arm := range me.Arms {
+		armEnv := object.NewEnclosedEnvironment(env)
+		if bindPattern(arm.Pattern, subject, armEnv) != nil {
+			continue
+		}
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, armEnv)
+			if isAbrupt(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+		return Eval(arm.Body, armEnv)
+	}
+
+	return newKindError(object.MATCH_ERROR, "match is not exhaustive: no arm matches %s", inspect(subject))
+}
+
+//evalDestructuring binds the names in the pattern of a let to the parts
+//of val. Nothing is bound unless the whole of val matches.
+func evalDestructuring(pattern ast.Pattern, val object.Object, env *object.Environment) object.Object {
+	if err := bindPattern(pattern, val, object.NewEnclosedEnvironment(env)); err != nil {
+		return newKindError(object.MATCH_ERROR, "cannot destructure: %s", err.Message)
+	}
+	bindPattern(pattern, val, env)
+	return nil
+}
+
+//bindPattern matches val against pattern and binds the names in the
+//pattern to the parts of val in env. If val does not match, it returns
+//an error saying why; names bound before the mismatch stay bound.
+func bindPattern(pattern ast.Pattern, val object.Object, env *object.Environment) *object.Error {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return nil
+
+	case *ast.BindingPattern:
+		env.Set(pattern.Name.Value, val)
+		return nil
+
+	case *ast.LiteralPattern:
+		lit := Eval(pattern.Value, env)
+		if err, ok := lit.(*object.Error); ok {
+			return err
+		}
+		if !literalMatches(lit, val) {
+			return newKindError(object.MATCH_ERROR, "%s does not match %s", inspect(val), pattern.String())
+		}
+		return nil
+
+	case *ast.ArrayPattern:
+		array, ok := val.(*object.Array)
+		if !ok {
+			return newKindError(object.MATCH_ERROR, "cannot match %s against array pattern %s", typeOf(val), pattern.String())
+		}
+		n := len(pattern.Elements)
+		if len(array.Elements) < n || pattern.Rest == nil && len(array.Elements) > n {
+			return newKindError(object.MATCH_ERROR, "array of length %d does not match %s", len(array.Elements), pattern.String())
+		}
+		for i, el := range pattern.Elements {
+			if err := bindPattern(el, array.Elements[i], env); err != nil {
+				return err
+			}
+		}
+		if pattern.Rest != nil {
+			rest := make([]object.Object, len(array.Elements)-n)
+			copy(rest, array.Elements[n:])
+			return bindPattern(pattern.Rest, &object.Array{Elements: rest}, env)
+		}
+		return nil
+
+	case *ast.HashPattern:
+		hash, ok := val.(*object.Hash)
+		if !ok {
+			return newKindError(object.MATCH_ERROR, "cannot match %s against hash pattern %s", typeOf(val), pattern.String())
+		}
+		for _, field := range pattern.Fields {
+			key := Eval(field.Key, env)
+			if err, ok := key.(*object.Error); ok {
+				return err
+			}
+			hashKey, ok := key.(object.Hashable)
+			if !ok {
+				return newKindError(object.TYPE_ERROR, "unusable as hash key: %s", key.Type())
+			}
+			pair, ok := hash.Get(hashKey.HashKey())
+			if !ok {
+				return newKindError(object.MATCH_ERROR, "hash has no key %s", inspect(key))
+			}
+			if err := bindPattern(field.Value, pair.Value, env); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return newError("unknown pattern: %T", pattern)
+}
+
+//literalMatches reports whether val equals the value of a literal
+//pattern. Numbers compare by value across int and float, and so do
+//strings, unlike with ==.
+func literalMatches(lit, val object.Object) bool {
+	if s, ok := lit.(*object.String); ok {
+		v, ok := val.(*object.String)
+		return ok && s.Value == v.Value
+	}
+	if val == nil {
+		return false
+	}
+	return evalInfixExpression("==", lit, val) == TRUE
+}
+
+//inspect is obj.Inspect(), with NULL for a missing value
+func inspect(obj object.Object) string {
+	if obj == nil {
+		return NULL.Inspect()
+	}
+	return obj.Inspect()
+}
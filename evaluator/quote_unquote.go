@@ -0,0 +1,84 @@
+package evaluator
+
+import (
+	"OSPLang/ast"
+	"OSPLang/object"
+	"OSPLang/token"
+	"fmt"
+)
+
+//quote returns node as code, after replacing each unquote(...) call in it
+//with the code for the value of its argument
+func quote(node ast.Node, env *object.Environment) object.Object {
+	var unquoted []ast.Node
+	var err *object.Error
+
+	node = ast.Modify(node, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || !isCallTo(call, "unquote") || err != nil {
+			return node
+		}
+		if len(call.Arguments) != 1 {
+			err = newKindError(object.ARGUMENT_ERROR, "wrong number of arguments to unquote. got=%d, want=1", len(call.Arguments))
+			err.Pos = call.Pos()
+			return node
+		}
+
+		value := Eval(call.Arguments[0], env)
+		if e, ok := value.(*object.Error); ok {
+			err = e
+			return node
+		}
+		code := objectToNode(value)
+		if code == nil {
+			err = newKindError(object.TYPE_ERROR, "cannot unquote %s", typeOf(value))
+			err.Pos = call.Pos()
+			return node
+		}
+		unquoted = append(unquoted, code)
+		return code
+	})
+
+	if err != nil {
+		return err
+	}
+	return &object.Quote{Node: node, Unquoted: unquoted}
+}
+
+//isCallTo reports whether call calls the identifier name
+func isCallTo(call *ast.CallExpression, name string) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == name
+}
+
+//objectToNode returns code that evaluates to obj, or nil if there is no
+//literal for it
+func objectToNode(obj object.Object) ast.Node {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		t := token.Token{Type: token.INT, Literal: fmt.Sprintf("%d", obj.Value)}
+		return &ast.IntegerLiteral{Token: t, Value: obj.Value}
+	case *object.Float:
+		t := token.Token{Type: token.FLOAT, Literal: obj.Inspect()}
+		return &ast.FloatLiteral{Token: t, Value: obj.Value}
+	case *object.Boolean:
+		t := token.Token{Type: token.FALSE, Literal: "false"}
+		if obj.Value {
+			t = token.Token{Type: token.TRUE, Literal: "true"}
+		}
+		return &ast.Boolean{Token: t, Value: obj.Value}
+	case *object.String:
+		t := token.Token{Type: token.STRING, Literal: obj.Value}
+		return &ast.StringLiteral{Token: t, Value: obj.Value}
+	case *object.Quote:
+		return obj.Node
+	}
+	return nil
+}
+
+func typeOf(obj object.Object) object.ObjectType {
+	if obj == nil {
+		return object.NULL_OBJ
+	}
+	return obj.Type()
+}
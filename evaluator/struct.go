@@ -0,0 +1,140 @@
+package evaluator
+
+import (
+	"OSPLang/ast"
+	"OSPLang/object"
+	"sort"
+)
+
+//evalStructStatement binds the name of a struct to its constructor. The
+//methods close over env, as function literals do.
+func evalStructStatement(ss *ast.StructStatement, env *object.Environment) object.Object {
+	st := &object.Struct{Name: ss.Name.Value, Methods: map[string]*object.Function{}}
+	for _, field := range ss.Fields {
+		st.Fields = append(st.Fields, field.Value)
+	}
+	for _, method := range ss.Methods {
+		fn := method.Function
+		st.Methods[method.Name.Value] = &object.Function{Parameters: fn.Parameters, Defaults: fn.Defaults, Rest: fn.Rest, Env: env, Body: fn.Body}
+	}
+	env.Set(st.Name, st)
+	return nil
+}
+
+//newInstance makes an instance of st for a call of its constructor. The
+//fields are filled from args in order, then by name from named; each
+//must be given exactly once.
+func newInstance(st *object.Struct, args []object.Object, named map[string]object.Object) object.Object {
+	if len(args) > len(st.Fields) {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments to %s. got=%d, want=%d", st.Name, len(args), len(st.Fields))
+	}
+	instance := &object.Instance{Struct: st, Fields: map[string]object.Object{}}
+	for i, arg := range args {
+		instance.Fields[st.Fields[i]] = arg
+	}
+
+	names := make([]string, 0, len(named))
+	for name := range named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !hasField(st, name) {
+			return newKindError(object.ARGUMENT_ERROR, "%s has no field %s", st.Name, name)
+		}
+		if _, ok := instance.Fields[name]; ok {
+			return newKindError(object.ARGUMENT_ERROR, "field %s given more than once", name)
+		}
+		instance.Fields[name] = named[name]
+	}
+
+	for _, name := range st.Fields {
+		val, ok := instance.Fields[name]
+		if !ok {
+			return newKindError(object.ARGUMENT_ERROR, "missing value for field %s of %s", name, st.Name)
+		}
+		if val == nil {
+			instance.Fields[name] = NULL
+		}
+	}
+	return instance
+}
+
+func hasField(st *object.Struct, name string) bool {
+	for _, field := range st.Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+//bindMethod returns method with self bound to instance
+func bindMethod(instance *object.Instance, method *object.Function) *object.Function {
+	env := object.NewEnclosedEnvironment(method.Env)
+	env.Set("self", instance)
+	bound := *method
+	bound.Env = env
+	return &bound
+}
+
+//instanceMember returns the field name of instance, or else its method
+//name bound to it. It returns nil if there is neither.
+func instanceMember(instance *object.Instance, name string) object.Object {
+	if val, ok := instance.Fields[name]; ok {
+		return val
+	}
+	if method, ok := instance.Struct.Methods[name]; ok {
+		return bindMethod(instance, method)
+	}
+	return nil
+}
+
+//evalFieldAssignment stores val in the field name of obj, which must be
+//an instance with such a field
+func evalFieldAssignment(obj object.Object, name string, val object.Object) object.Object {
+	instance, ok := obj.(*object.Instance)
+	if !ok {
+		return newKindError(object.TYPE_ERROR, "cannot assign to member %s of %s", name, typeOf(obj))
+	}
+	if _, ok := instance.Fields[name]; !ok {
+		return newKindError(object.NAME_ERROR, "%s has no field %s", instance.Struct.Name, name)
+	}
+	instance.Fields[name] = val
+	return val
+}
+
+//instancesEqual reports whether a and b are instances of the same struct
+//with equal fields. Fields compare as == does, except that strings
+//compare by value.
+func instancesEqual(a, b *object.Instance) bool {
+	return fieldsEqual(a, b, map[[2]*object.Instance]bool{})
+}
+
+//fieldsEqual is instancesEqual for instances that may hold themselves,
+//directly or not. seen holds the pairs being compared; meeting one of
+//them again adds nothing, so it counts as equal.
+func fieldsEqual(a, b *object.Instance, seen map[[2]*object.Instance]bool) bool {
+	if a.Struct != b.Struct {
+		return false
+	}
+	pair := [2]*object.Instance{a, b}
+	if seen[pair] {
+		return true
+	}
+	seen[pair] = true
+	defer delete(seen, pair)
+
+	for _, name := range a.Struct.Fields {
+		x, xok := a.Fields[name].(*object.Instance)
+		y, yok := b.Fields[name].(*object.Instance)
+		if xok && yok {
+			if !fieldsEqual(x, y, seen) {
+				return false
+			}
+		} else if !literalMatches(a.Fields[name], b.Fields[name]) {
+			return false
+		}
+	}
+	return true
+}
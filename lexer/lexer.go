@@ -2,38 +2,211 @@ package lexer
 
 import (
 	"OSPLang/token"
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 //Lexer is ...
 type Lexer struct {
-	input        string
+	reader       *bufio.Reader
+	err          error
+	eof          bool
+	filename     string
 	position     int
 	readPosition int
-	ch           byte
+	ch           rune
+	raw          []byte // source bytes of ch
+	line         int
+	column       int
+	mode         Mode
+	leading      []token.Trivia
+	recording    bool
+	text         []byte // bytes read since mark
+	diagnostics  []Diagnostic
+	tokenDiags   int   // len(diagnostics) when the current token started
+	interps      []int // brace depth inside each open ${ of a string
 }
 
+//Diagnostic describes a problem in the source met by the lexer. The
+//offending text is also returned as the literal of an ILLEGAL token.
+type Diagnostic struct {
+	Pos    token.Position
+	End    token.Position // position just past Char
+	Char   string         // the offending character or characters
+	Reason string
+}
+
+func (d Diagnostic) String() string {
+	return d.Pos.String() + ": " + d.Reason
+}
+
+//Mode controls optional behaviour of the lexer
+type Mode uint
+
+const (
+	//KeepTrivia attaches skipped whitespace and comments to the next token
+	KeepTrivia Mode = 1 << iota
+)
+
 //New is ...
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewFile("", strings.NewReader(input))
+}
+
+//NewReader returns a Lexer that reads its input from r in buffered
+//chunks, so only a small window of the source is held in memory at once
+func NewReader(r io.Reader) *Lexer {
+	return NewFile("", r)
+}
+
+//NewFile is NewReader with token positions that carry the given file name
+func NewFile(filename string, r io.Reader) *Lexer {
+	l := &Lexer{reader: bufio.NewReader(r), filename: filename, line: 1}
 	l.readChar()
 	return l
 }
 
+//Err returns the first error other than io.EOF met while reading input.
+//The lexer treats such an error as the end of input.
+func (l *Lexer) Err() error {
+	return l.err
+}
+
 func (l *Lexer) readChar() {
-	if l.readPosition >= len(l.input) {
+	if l.eof {
+		return
+	}
+	if l.recording {
+		l.text = append(l.text, l.raw...)
+	}
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.position = l.readPosition
+	l.raw = l.raw[:0]
+	r, size := l.decode(0)
+	if size == 0 {
 		l.ch = 0
+		l.eof = true
 	} else {
-		l.ch = l.input[l.readPosition]
+		b, _ := l.reader.Peek(size)
+		l.raw = append(l.raw, b...)
+		l.reader.Discard(size)
+		l.ch = r
+		l.readPosition += size
 	}
-	l.position = l.readPosition
-	l.readPosition++
+	l.column++
+}
+
+//decode returns the character starting offset bytes past the current one
+//and its width, without consuming input. The width is 0 at end of input.
+func (l *Lexer) decode(offset int) (rune, int) {
+	b, err := l.reader.Peek(offset + 1)
+	if len(b) <= offset {
+		if err != nil && err != io.EOF && l.err == nil {
+			l.err = err
+		}
+		return 0, 0
+	}
+	b, _ = l.reader.Peek(offset + runeWidth(b[offset]))
+	return utf8.DecodeRune(b[offset:])
+}
+
+//runeWidth is the length of the UTF-8 sequence that lead starts
+func runeWidth(lead byte) int {
+	switch {
+	case lead >= 0xF0:
+		return 4
+	case lead >= 0xE0:
+		return 3
+	case lead >= 0xC0:
+		return 2
+	}
+	return 1
+}
+
+//mark starts recording the source text from the current character on
+func (l *Lexer) mark() {
+	l.recording = true
+	l.text = l.text[:0]
+}
+
+//captured stops recording and returns the text read since mark, up to but
+//not including the current character
+func (l *Lexer) captured() string {
+	l.recording = false
+	return string(l.text)
+}
+
+//invalidChar reports whether the current character is a byte that is
+//not valid UTF-8
+func (l *Lexer) invalidChar() bool {
+	return l.ch == utf8.RuneError && len(l.raw) == 1
+}
+
+//Diagnostics returns the problems found in the input read so far
+func (l *Lexer) Diagnostics() []Diagnostic {
+	return l.diagnostics
+}
+
+func (l *Lexer) report(pos token.Position, char, reason string) {
+	end := pos
+	end.Offset += len(char)
+	end.Column += utf8.RuneCountInString(char)
+	l.diagnostics = append(l.diagnostics, Diagnostic{Pos: pos, End: end, Char: char, Reason: reason})
+}
+
+//SetMode changes the lexer's mode for the tokens that follow
+func (l *Lexer) SetMode(mode Mode) {
+	l.mode = mode
+}
+
+//pos is the position of the current character
+func (l *Lexer) pos() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.position,
+		Line:     l.line,
+		Column:   l.column,
+	}
+}
+
+//locate stamps tok with its start position, the current position as its
+//end and any trivia read before it. An ILLEGAL token takes the offending
+//text of the first diagnostic reported while reading it.
+func (l *Lexer) locate(tok token.Token, start token.Position) token.Token {
+	if tok.Type == token.ILLEGAL && len(l.diagnostics) > l.tokenDiags {
+		tok.Literal = l.diagnostics[l.tokenDiags].Char
+	}
+	tok.Pos = start
+	tok.End = l.pos()
+	tok.Leading = l.leading
+	l.leading = nil
+	return tok
 }
 
 //NextToken is ...
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
-	l.skipWhitespaces()
+	l.tokenDiags = len(l.diagnostics)
+	start, ok := l.readTrivia()
+	if !ok {
+		l.report(start, "/*", "unterminated block comment")
+		tok.Type = token.ILLEGAL
+		return l.locate(tok, start)
+	}
+	if l.eof {
+		tok.Literal = ""
+		tok.Type = token.EOF
+		return l.locate(tok, start)
+	}
 
 	switch l.ch {
 	case '=':
@@ -41,14 +214,18 @@ func (l *Lexer) NextToken() token.Token {
 			ch := l.ch
 			l.readChar()
 			tok = token.Token{Type: token.EQ, Literal: string(ch) + string(l.ch)}
+		} else if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: string(ch) + string(l.ch)}
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		tok = l.withAssign(token.PLUS, token.PLUS_ASSIGN)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		tok = l.withAssign(token.MINUS, token.MINUS_ASSIGN)
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -58,13 +235,45 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		tok = l.withAssign(token.SLASH, token.SLASH_ASSIGN)
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		tok = l.withAssign(token.ASTERISK, token.ASTERISK_ASSIGN)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.LT_EQ, Literal: string(ch) + string(l.ch)}
+		} else {
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.GT_EQ, Literal: string(ch) + string(l.ch)}
+		} else {
+			tok = newToken(token.GT, l.ch)
+		}
+	case '&':
+		if l.peekChar() == '&' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.AND, Literal: string(ch) + string(l.ch)}
+		} else {
+			tok = l.unexpected(start)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.OR, Literal: string(ch) + string(l.ch)}
+		} else if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.PIPE, Literal: string(ch) + string(l.ch)}
+		} else {
+			tok = l.unexpected(start)
+		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case ',':
@@ -74,86 +283,526 @@ func (l *Lexer) NextToken() token.Token {
 	case ')':
 		tok = newToken(token.RPAREN, l.ch)
 	case '{':
+		if n := len(l.interps); n > 0 {
+			l.interps[n-1]++
+		}
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
-		tok = newToken(token.RBRACE, l.ch)
-	case 0:
-		tok.Literal = ""
-		tok.Type = token.EOF
+		n := len(l.interps)
+		if n == 0 || l.interps[n-1] > 0 {
+			if n > 0 {
+				l.interps[n-1]--
+			}
+			tok = newToken(token.RBRACE, l.ch)
+			break
+		}
+		// the } closes an embedded expression; the string goes on
+		tok.Type, tok.Literal = l.readString(start, token.INTERP_TAIL, token.INTERP_MID)
+		if tok.Type != token.INTERP_MID {
+			l.interps = l.interps[:n-1]
+		}
+		if tok.Type == token.ILLEGAL && l.eof {
+			return l.locate(tok, start)
+		}
 	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		if l.peekChar() == '"' && l.peekAt(1) == '"' {
+			tok.Type, tok.Literal = l.readHeredoc(start)
+		} else {
+			tok.Type, tok.Literal = l.readString(start, token.STRING, token.INTERP_HEAD)
+		}
+		if tok.Type == token.INTERP_HEAD {
+			l.interps = append(l.interps, 0)
+		}
+		if tok.Type == token.ILLEGAL && l.eof {
+			return l.locate(tok, start)
+		}
+	case '`':
+		tok.Type, tok.Literal = l.readRawString(start)
+		if tok.Type == token.ILLEGAL {
+			return l.locate(tok, start)
+		}
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
+	case '.':
+		if l.peekChar() == '.' && l.peekAt(1) == '.' {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.DOT, l.ch)
+		}
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
-			return tok
+			return l.locate(tok, start)
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
-			return tok
+			tok.Type, tok.Literal = l.readNumber()
+			return l.locate(tok, start)
+		} else if l.invalidChar() {
+			l.report(start, string(l.raw), fmt.Sprintf("invalid UTF-8 encoding %q", l.raw))
+			tok.Type = token.ILLEGAL
 		} else {
-			tok = newToken(token.ILLEGAL, l.ch)
+			tok = l.unexpected(start)
 		}
 
 	}
 	l.readChar()
-	return tok
+	return l.locate(tok, start)
+}
+
+//readTrivia skips whitespace and comments, keeping them for the next
+//token when the lexer keeps trivia. Their text is only recorded then, so
+//a long comment is not held in memory otherwise. It returns the position
+//of the first token character, or of the opening /* and false if a block
+//comment is never closed.
+func (l *Lexer) readTrivia() (token.Position, bool) {
+	keep := l.mode&KeepTrivia != 0
+	for {
+		start := l.pos()
+		if keep {
+			l.mark()
+		}
+		var kind token.TriviaKind
+		switch {
+		case isWhitespace(l.ch):
+			l.skipWhitespaces()
+			kind = token.WHITESPACE
+		case l.ch == '#', l.ch == '/' && l.peekChar() == '/':
+			l.skipLineComment()
+			kind = token.LINE_COMMENT
+		case l.ch == '/' && l.peekChar() == '*':
+			if !l.skipBlockComment() {
+				l.recording = false
+				return start, false
+			}
+			kind = token.BLOCK_COMMENT
+		default:
+			l.recording = false
+			return start, true
+		}
+		if keep {
+			l.leading = append(l.leading, token.Trivia{
+				Kind: kind,
+				Text: l.captured(),
+				Pos:  start,
+			})
+		}
+	}
+}
+
+func (l *Lexer) skipLineComment() {
+	for l.ch != '\n' && !l.eof {
+		l.readChar()
+	}
+}
+
+//skipBlockComment moves past a possibly nested /* */ comment and reports
+//whether it was closed before the end of input
+func (l *Lexer) skipBlockComment() bool {
+	depth := 0
+	for !l.eof {
+		switch {
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+			if depth == 0 {
+				l.readChar()
+				return true
+			}
+		}
+		l.readChar()
+	}
+	return false
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
+//withAssign returns a token for the current operator character, or for
+//the compound assignment it forms with a following '='
+func (l *Lexer) withAssign(op, assign token.TokenType) token.Token {
+	if l.peekChar() == '=' {
+		ch := l.ch
+		l.readChar()
+		return token.Token{Type: assign, Literal: string(ch) + string(l.ch)}
+	}
+	return newToken(op, l.ch)
+}
+
+//unexpected reports the current character as one that cannot start a token
+func (l *Lexer) unexpected(start token.Position) token.Token {
+	l.report(start, string(l.ch), fmt.Sprintf("unexpected character %q", l.ch))
+	return newToken(token.ILLEGAL, l.ch)
+}
+
 func (l *Lexer) readIdentifier() string {
-	position := l.position
-	for isLetter(l.ch) {
+	l.mark()
+	for isLetter(l.ch) || isIdentPart(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	return l.captured()
 }
 
-func isLetter(ch byte) bool {
+//isLetter reports whether ch can start an identifier: '_' or a character
+//with the Unicode ID_Start property
+func isLetter(ch rune) bool {
 	return 'a' <= ch && ch <= 'z' ||
 		'A' <= ch && ch <= 'Z' ||
-		ch == '_'
+		ch == '_' ||
+		ch >= utf8.RuneSelf && unicode.In(ch, unicode.Letter, unicode.Nl, unicode.Other_ID_Start)
+}
+
+//isIdentPart reports whether ch can continue an identifier, per the
+//Unicode ID_Continue property
+func isIdentPart(ch rune) bool {
+	return isDigit(ch) ||
+		ch >= utf8.RuneSelf && unicode.In(ch, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue)
+}
+
+func isWhitespace(ch rune) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
 }
 
 func (l *Lexer) skipWhitespaces() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+	for isWhitespace(l.ch) {
+		l.readChar()
+	}
+}
+
+//readNumber reads an integer or a float with an optional fraction and
+//exponent, such as 3, 3.14, 1e9 or 2.5E-3. Integers may also carry a
+//0x, 0o or 0b base prefix, and any number may use '_' between digits as
+//in 1_000_000. A malformed number is reported and comes back as ILLEGAL.
+func (l *Lexer) readNumber() (token.TokenType, string) {
+	start := l.pos()
+	diags := len(l.diagnostics)
+	l.mark()
+	tokenType := token.TokenType(token.INT)
+	if base, name := basePrefix(l.peekChar()); l.ch == '0' && base != 0 {
+		l.readChar()
+		l.readChar()
+		l.readPrefixedDigits(start, base, name)
+	} else {
+		l.skipDigits(isDigit, false)
+		if l.ch == '.' && isDigit(l.peekChar()) {
+			tokenType = token.FLOAT
+			l.readChar()
+			l.skipDigits(isDigit, false)
+		}
+		if l.ch == 'e' || l.ch == 'E' {
+			tokenType = token.FLOAT
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			if !isDigit(l.ch) {
+				l.report(start, string(l.text), "exponent has no digits")
+			}
+			l.skipDigits(isDigit, false)
+		}
+		// a 0 in front of an integer would read as C's octal, so it is
+		// not allowed: octal is written 0o17
+		if tokenType == token.INT && len(l.text) > 1 && l.text[0] == '0' {
+			l.report(start, string(l.text), "decimal literal has a leading zero; use 0o for octal")
+		}
+	}
+	literal := l.captured()
+	if len(l.diagnostics) > diags {
+		return token.ILLEGAL, literal
+	}
+	return tokenType, literal
+}
+
+//readPrefixedDigits reads the digits of a 0x, 0o or 0b literal whose
+//prefix has been consumed. Any letters or digits that follow are taken as
+//part of the literal so a stray one is reported rather than lexed apart.
+func (l *Lexer) readPrefixedDigits(start token.Position, base int, name string) {
+	valid := func(ch rune) bool { return digitValue(ch) < base }
+	invalid := 0
+	for {
+		l.skipDigits(valid, true)
+		if !isLetter(l.ch) && !isDigit(l.ch) {
+			break
+		}
+		if invalid == 0 {
+			l.report(l.pos(), string(l.ch), fmt.Sprintf("invalid digit %q in %s literal", l.ch, name))
+		}
+		invalid++
 		l.readChar()
 	}
+	if invalid == 0 && strings.Trim(string(l.text[2:]), "_") == "" {
+		l.report(start, string(l.text), name+" literal has no digits")
+	}
 }
 
-func (l *Lexer) readNumber() string {
-	position := l.position
-	for isDigit(l.ch) {
+func basePrefix(ch rune) (int, string) {
+	switch ch {
+	case 'x', 'X':
+		return 16, "hexadecimal"
+	case 'o', 'O':
+		return 8, "octal"
+	case 'b', 'B':
+		return 2, "binary"
+	}
+	return 0, ""
+}
+
+//digitValue is the value of ch as a digit, or 36 if it is not one
+func digitValue(ch rune) int {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return int(ch - '0')
+	case 'a' <= ch && ch <= 'z':
+		return int(ch-'a') + 10
+	case 'A' <= ch && ch <= 'Z':
+		return int(ch-'A') + 10
+	}
+	return 36
+}
+
+//skipDigits moves past digits and the '_' separators between them,
+//reporting any '_' that does not sit between two digits. afterPrefix
+//allows a '_' straight after a base prefix, as in 0x_FF.
+func (l *Lexer) skipDigits(digit func(rune) bool, afterPrefix bool) {
+	prevDigit := afterPrefix
+	prevSep := false
+	for digit(l.ch) || l.ch == '_' {
+		sep := l.ch == '_'
+		if sep && !prevSep && (!prevDigit || !digit(l.peekChar())) {
+			l.report(l.pos(), "_", "'_' must separate successive digits")
+		}
+		prevDigit, prevSep = !sep, sep
 		l.readChar()
 	}
-	return l.input[position:l.position]
 }
-func isDigit(ch byte) bool {
+
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
-		return 0
-	} else {
-		return l.input[l.readPosition]
+func (l *Lexer) peekChar() rune {
+	r, _ := l.decode(0)
+	return r
+}
+
+//peekAt returns the character offset bytes past the next one. It is only
+//meant for looking past single-byte characters.
+func (l *Lexer) peekAt(offset int) rune {
+	r, _ := l.decode(offset)
+	return r
+}
+
+//readString reads the text of a double-quoted literal, from the opening
+//quote or from the } that ends an embedded expression, and decodes its
+//escape sequences. It stops at the closing quote and returns closed, or
+//at a ${ and returns open. A literal with a malformed escape is reported
+//and read on to the closing quote, or the end of input, and comes back as
+//ILLEGAL. The parts of an interpolated literal keep their types so the
+//parser can still match them up.
+func (l *Lexer) readString(start token.Position, closed, open token.TokenType) (token.TokenType, string) {
+	var out strings.Builder
+	ok := true
+	for {
+		l.readChar()
+		if l.eof {
+			l.report(start, "\"", "unterminated string literal")
+			return token.ILLEGAL, ""
+		}
+		switch l.ch {
+		case '"':
+			if !ok && closed == token.STRING {
+				return token.ILLEGAL, ""
+			}
+			return closed, out.String()
+		case '$':
+			if l.peekChar() != '{' {
+				out.WriteByte('$')
+				break
+			}
+			l.readChar()
+			return open, out.String()
+		case '\\':
+			escape := l.pos()
+			l.readChar()
+			if !l.readEscape(&out, escape) {
+				ok = false
+			}
+		default:
+			// copy the source bytes so multibyte and even malformed
+			// sequences pass through untouched
+			out.Write(l.raw)
+		}
+	}
+}
+
+//readEscape decodes the escape sequence whose first character, after the
+//backslash at pos, is the current character
+func (l *Lexer) readEscape(out *strings.Builder, pos token.Position) bool {
+	if l.eof {
+		// readString reports the unterminated literal
+		return true
+	}
+	switch l.ch {
+	case 'n':
+		out.WriteByte('\n')
+	case 't':
+		out.WriteByte('\t')
+	case 'r':
+		out.WriteByte('\r')
+	case '0':
+		out.WriteByte(0)
+	case '\\', '"', '\'', '$':
+		out.WriteRune(l.ch)
+	case 'x':
+		// \xHH is the code point U+00HH
+		digits := l.readHexDigits(2)
+		if len(digits) != 2 {
+			return l.badEscape(pos, "\\x"+digits, "want exactly 2 hex digits")
+		}
+		return l.writeCodePoint(out, pos, digits, "\\x"+digits)
+	case 'u':
+		// \uHHHH or \u{H...}
+		if l.peekChar() != '{' {
+			digits := l.readHexDigits(4)
+			if len(digits) != 4 {
+				return l.badEscape(pos, "\\u"+digits, "want exactly 4 hex digits")
+			}
+			return l.writeCodePoint(out, pos, digits, "\\u"+digits)
+		}
+		l.readChar()
+		digits := l.readHexDigits(6)
+		if l.peekChar() != '}' || len(digits) == 0 {
+			return l.badEscape(pos, "\\u{"+digits, "want 1 to 6 hex digits and a closing }")
+		}
+		l.readChar()
+		return l.writeCodePoint(out, pos, digits, "\\u{"+digits+"}")
+	default:
+		escape := "\\" + string(l.ch)
+		l.report(pos, escape, "unknown escape sequence "+escape)
+		return false
+	}
+	return true
+}
+
+func (l *Lexer) badEscape(pos token.Position, escape, why string) bool {
+	l.report(pos, escape, "invalid escape sequence "+escape+": "+why)
+	return false
+}
+
+//readHexDigits reads up to max hex digits following the current character
+func (l *Lexer) readHexDigits(max int) string {
+	var digits strings.Builder
+	for i := 0; i < max && isHexDigit(l.peekChar()); i++ {
+		l.readChar()
+		digits.WriteRune(l.ch)
+	}
+	return digits.String()
+}
+
+func (l *Lexer) writeCodePoint(out *strings.Builder, pos token.Position, digits, escape string) bool {
+	cp, _ := strconv.ParseUint(digits, 16, 32)
+	r := rune(cp)
+	if !utf8.ValidRune(r) {
+		return l.badEscape(pos, escape, "not a valid Unicode code point")
 	}
+	out.WriteRune(r)
+	return true
+}
 
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
 }
 
-func (l *Lexer) readString() string {
-	position := l.position + 1
+//readRawString reads a backtick-delimited literal. Its text is taken as
+//is, newlines included, except that carriage returns are dropped so a
+//script reads the same on every platform.
+func (l *Lexer) readRawString(start token.Position) (token.TokenType, string) {
+	var out strings.Builder
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
-			break
+		if l.eof {
+			l.report(start, "`", "unterminated raw string literal")
+			return token.ILLEGAL, ""
+		}
+		switch l.ch {
+		case '`':
+			return token.STRING, out.String()
+		case '\r':
+		default:
+			out.Write(l.raw)
+		}
+	}
+}
+
+//readHeredoc reads a """ literal. Like a raw string it has no escape
+//sequences; its lines are then passed through dedent.
+func (l *Lexer) readHeredoc(start token.Position) (token.TokenType, string) {
+	l.readChar()
+	l.readChar()
+	var out strings.Builder
+	for {
+		l.readChar()
+		switch {
+		case l.eof:
+			l.report(start, `"""`, "unterminated heredoc")
+			return token.ILLEGAL, ""
+		case l.ch == '"' && l.peekChar() == '"' && l.peekAt(1) == '"':
+			l.readChar()
+			l.readChar()
+			return token.STRING, dedent(out.String())
+		case l.ch == '\r':
+		default:
+			out.Write(l.raw)
 		}
 	}
-	return l.input[position:l.position]
+}
+
+//dedent strips the indentation shared by the lines of a heredoc. A first
+//line holding only whitespace, the rest of the line with the opening
+//quotes, is dropped. The last line, the one with the closing quotes, and
+//every line that is not blank count towards the shared indentation, so
+//the closing quotes can be moved left to keep some of it. Blank lines
+//come out empty.
+func dedent(s string) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) > 1 && isBlank(lines[0]) {
+		lines = lines[1:]
+	}
+	margin, found := "", false
+	for i, line := range lines {
+		if isBlank(line) && i != len(lines)-1 {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			margin, found = indent, true
+			continue
+		}
+		n := 0
+		for n < len(margin) && n < len(indent) && margin[n] == indent[n] {
+			n++
+		}
+		margin = margin[:n]
+	}
+	for i, line := range lines {
+		if isBlank(line) {
+			lines[i] = ""
+		} else {
+			lines[i] = line[len(margin):]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isBlank(line string) bool {
+	return strings.TrimLeft(line, " \t") == ""
 }
@@ -2,12 +2,17 @@ package lexer
 
 import (
 	"OSPLang/token"
+	"errors"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestNextToken(t *testing.T) {
 	input := `
 	{"foo": "bar"}
+	[1, 2];
 	`
 
 	tests := []struct {
@@ -20,6 +25,12 @@ func TestNextToken(t *testing.T) {
 		{token.COLON, ":"},
 		{token.STRING, "bar"},
 		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
 		{token.EOF, ""},
 	}
 	l := New(input)
@@ -35,3 +46,582 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  foo(x)"
+
+	tests := []struct {
+		expectedLiteral string
+		expectedPos     token.Position
+		expectedEnd     token.Position
+	}{
+		{"let", token.Position{Filename: "a.osp", Offset: 0, Line: 1, Column: 1}, token.Position{Filename: "a.osp", Offset: 3, Line: 1, Column: 4}},
+		{"x", token.Position{Filename: "a.osp", Offset: 4, Line: 1, Column: 5}, token.Position{Filename: "a.osp", Offset: 5, Line: 1, Column: 6}},
+		{"=", token.Position{Filename: "a.osp", Offset: 6, Line: 1, Column: 7}, token.Position{Filename: "a.osp", Offset: 7, Line: 1, Column: 8}},
+		{"5", token.Position{Filename: "a.osp", Offset: 8, Line: 1, Column: 9}, token.Position{Filename: "a.osp", Offset: 9, Line: 1, Column: 10}},
+		{";", token.Position{Filename: "a.osp", Offset: 9, Line: 1, Column: 10}, token.Position{Filename: "a.osp", Offset: 10, Line: 1, Column: 11}},
+		{"foo", token.Position{Filename: "a.osp", Offset: 13, Line: 2, Column: 3}, token.Position{Filename: "a.osp", Offset: 16, Line: 2, Column: 6}},
+		{"(", token.Position{Filename: "a.osp", Offset: 16, Line: 2, Column: 6}, token.Position{Filename: "a.osp", Offset: 17, Line: 2, Column: 7}},
+		{"x", token.Position{Filename: "a.osp", Offset: 17, Line: 2, Column: 7}, token.Position{Filename: "a.osp", Offset: 18, Line: 2, Column: 8}},
+		{")", token.Position{Filename: "a.osp", Offset: 18, Line: 2, Column: 8}, token.Position{Filename: "a.osp", Offset: 19, Line: 2, Column: 9}},
+		{"", token.Position{Filename: "a.osp", Offset: 19, Line: 2, Column: 9}, token.Position{Filename: "a.osp", Offset: 19, Line: 2, Column: 9}},
+	}
+	l := NewFile("a.osp", strings.NewReader(input))
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos != tt.expectedPos {
+			t.Errorf("tests[%d] - pos wrong. expected=%+v, got=%+v",
+				i, tt.expectedPos, tok.Pos)
+		}
+		if tok.End != tt.expectedEnd {
+			t.Errorf("tests[%d] - end wrong. expected=%+v, got=%+v",
+				i, tt.expectedEnd, tok.End)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `let x = 5; // the answer
+	# shell style
+	/* block /* nested */ still comment */ x / 2;
+	/* never closed`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.ILLEGAL, "/*"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Leading != nil {
+			t.Fatalf("tests[%d] - trivia kept without KeepTrivia. got=%+v", i, tok.Leading)
+		}
+	}
+}
+
+func TestKeepTrivia(t *testing.T) {
+	input := "// doc\nlet /* a */ x"
+
+	l := New(input)
+	l.SetMode(KeepTrivia)
+
+	tok := l.NextToken()
+	expected := []token.Trivia{
+		{Kind: token.LINE_COMMENT, Text: "// doc", Pos: token.Position{Offset: 0, Line: 1, Column: 1}},
+		{Kind: token.WHITESPACE, Text: "\n", Pos: token.Position{Offset: 6, Line: 1, Column: 7}},
+	}
+	if tok.Type != token.LET || len(tok.Leading) != len(expected) {
+		t.Fatalf("wrong token or trivia. got=%q %+v", tok.Type, tok.Leading)
+	}
+	for i, tr := range expected {
+		if tok.Leading[i] != tr {
+			t.Errorf("trivia[%d] wrong. expected=%+v, got=%+v", i, tr, tok.Leading[i])
+		}
+	}
+
+	tok = l.NextToken()
+	if tok.Type != token.IDENT || len(tok.Leading) != 3 || tok.Leading[1].Text != "/* a */" {
+		t.Fatalf("wrong token or trivia. got=%q %+v", tok.Type, tok.Leading)
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedDiag    string
+	}{
+		{`"plain"`, token.STRING, "plain", ""},
+		{`"say \"hi\""`, token.STRING, `say "hi"`, ""},
+		{`"a\nb\tc\r\\"`, token.STRING, "a\nb\tc\r\\", ""},
+		{`"nul\0'\'"`, token.STRING, "nul\x00''", ""},
+		{`"\x41\x7e"`, token.STRING, "A~", ""},
+		{`"é\u{1F600}"`, token.STRING, "é😀", ""},
+		{`"café 😀"`, token.STRING, "café 😀", ""},
+		{`"oops`, token.ILLEGAL, `"`, "1:1: unterminated string literal"},
+		{`"bad \q"`, token.ILLEGAL, `\q`, `1:6: unknown escape sequence \q`},
+		{`"\x4"`, token.ILLEGAL, `\x4`, `1:2: invalid escape sequence \x4: want exactly 2 hex digits`},
+		{`"\u12"`, token.ILLEGAL, `\u12`, `1:2: invalid escape sequence \u12: want exactly 4 hex digits`},
+		{`"\u{110000}"`, token.ILLEGAL, `\u{110000}`, `1:2: invalid escape sequence \u{110000}: not a valid Unicode code point`},
+		{`"\u{1F600"`, token.ILLEGAL, `\u{1F600`, `1:2: invalid escape sequence \u{1F600: want 1 to 6 hex digits and a closing }`},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Errorf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Errorf("tests[%d] - expected EOF after string. got=%q", i, next.Type)
+		}
+		testDiagnostic(t, i, l, tt.expectedDiag)
+	}
+}
+
+func TestRawStrings(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedDiag    string
+	}{
+		{"`C:\\dir\\n`", token.STRING, `C:\dir\n`, ""},
+		{"`line 1\r\n  \"line 2\"`", token.STRING, "line 1\n  \"line 2\"", ""},
+		{"``", token.STRING, "", ""},
+		{`""`, token.STRING, "", ""},
+		{`"""one \n line"""`, token.STRING, `one \n line`, ""},
+		{"\"\"\"\n    SELECT *\n      FROM t\n\n    WHERE x\"\"\"", token.STRING, "SELECT *\n  FROM t\n\nWHERE x", ""},
+		{"\"\"\"\n    {\"a\": 1}\n    \"\"\"", token.STRING, "{\"a\": 1}\n", ""},
+		{"\"\"\"\n    keep\n  \"\"\"", token.STRING, "  keep\n", ""},
+		{"`open", token.ILLEGAL, "`", "1:1: unterminated raw string literal"},
+		{"\"\"\"\nopen\"\"", token.ILLEGAL, `"""`, "1:1: unterminated heredoc"},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Errorf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Errorf("tests[%d] - expected EOF after string. got=%q", i, next.Type)
+		}
+		testDiagnostic(t, i, l, tt.expectedDiag)
+	}
+}
+
+func testDiagnostic(t *testing.T, i int, l *Lexer, expected string) {
+	diags := l.Diagnostics()
+	if expected == "" {
+		if len(diags) != 0 {
+			t.Errorf("tests[%d] - unexpected diagnostics. got=%v", i, diags)
+		}
+		return
+	}
+	if len(diags) == 0 {
+		t.Errorf("tests[%d] - no diagnostics. expected=%q", i, expected)
+		return
+	}
+	if diags[0].String() != expected {
+		t.Errorf("tests[%d] - diagnostic wrong. expected=%q, got=%q",
+			i, expected, diags[0].String())
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	input := "let größe = 1; δx2 + 名前_1 + é \xff"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{token.LET, "let", 1},
+		{token.IDENT, "größe", 5},
+		{token.ASSIGN, "=", 11},
+		{token.INT, "1", 13},
+		{token.SEMICOLON, ";", 14},
+		{token.IDENT, "δx2", 16},
+		{token.PLUS, "+", 20},
+		{token.IDENT, "名前_1", 22},
+		{token.PLUS, "+", 27},
+		{token.IDENT, "é", 29},
+		{token.ILLEGAL, "\xff", 32},
+		{token.EOF, "", 33},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - column wrong. expected=%d, got=%d",
+				i, tt.expectedColumn, tok.Pos.Column)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedDiag    string
+	}{
+		{"42", token.INT, "42", ""},
+		{"3.14", token.FLOAT, "3.14", ""},
+		{"0.5", token.FLOAT, "0.5", ""},
+		{"1e9", token.FLOAT, "1e9", ""},
+		{"2.5E-3", token.FLOAT, "2.5E-3", ""},
+		{"6e+2", token.FLOAT, "6e+2", ""},
+		{"1e", token.ILLEGAL, "1e", "1:1: exponent has no digits"},
+		{"1e;", token.ILLEGAL, "1e", "1:1: exponent has no digits"},
+		{"1.5e+x", token.ILLEGAL, "1.5e+", "1:1: exponent has no digits"},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Errorf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		testDiagnostic(t, i, l, tt.expectedDiag)
+		if diags := l.Diagnostics(); len(diags) > 0 && diags[0].Char != tt.expectedLiteral {
+			t.Errorf("tests[%d] - diagnostic char wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, diags[0].Char)
+		}
+	}
+}
+
+func TestIntegerForms(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedDiag    string
+	}{
+		{"0xFF", token.INT, "0xFF", ""},
+		{"0Xdead_beef", token.INT, "0Xdead_beef", ""},
+		{"0o17", token.INT, "0o17", ""},
+		{"0b1010", token.INT, "0b1010", ""},
+		{"0b_1010", token.INT, "0b_1010", ""},
+		{"1_000_000", token.INT, "1_000_000", ""},
+		{"1_000.000_1", token.FLOAT, "1_000.000_1", ""},
+		{"0", token.INT, "0", ""},
+		{"0xZZ", token.ILLEGAL, "Z", "1:3: invalid digit 'Z' in hexadecimal literal"},
+		{"0o19", token.ILLEGAL, "9", "1:4: invalid digit '9' in octal literal"},
+		{"0b102", token.ILLEGAL, "2", "1:5: invalid digit '2' in binary literal"},
+		{"0x", token.ILLEGAL, "0x", "1:1: hexadecimal literal has no digits"},
+		{"1_000_", token.ILLEGAL, "_", "1:6: '_' must separate successive digits"},
+		{"1__0", token.ILLEGAL, "_", "1:2: '_' must separate successive digits"},
+		{"0xF_", token.ILLEGAL, "_", "1:4: '_' must separate successive digits"},
+		{"1_.5", token.ILLEGAL, "_", "1:2: '_' must separate successive digits"},
+		{"017", token.ILLEGAL, "017", "1:1: decimal literal has a leading zero; use 0o for octal"},
+		{"09", token.ILLEGAL, "09", "1:1: decimal literal has a leading zero; use 0o for octal"},
+		{"0_7", token.ILLEGAL, "0_7", "1:1: decimal literal has a leading zero; use 0o for octal"},
+		{"00", token.ILLEGAL, "00", "1:1: decimal literal has a leading zero; use 0o for octal"},
+		{"0.5", token.FLOAT, "0.5", ""},
+		{"01.5", token.FLOAT, "01.5", ""},
+		{"0e3", token.FLOAT, "0e3", ""},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Errorf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Errorf("tests[%d] - expected EOF after number. got=%q (%q)", i, next.Type, next.Literal)
+		}
+		testDiagnostic(t, i, l, tt.expectedDiag)
+	}
+}
+
+func TestComparisonAndLogicalTokens(t *testing.T) {
+	input := "a <= b >= c && d || e < f > g"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.AND, "&&"},
+		{token.IDENT, "d"},
+		{token.OR, "||"},
+		{token.IDENT, "e"},
+		{token.LT, "<"},
+		{token.IDENT, "f"},
+		{token.GT, ">"},
+		{token.IDENT, "g"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	input := strings.Repeat("let größe = fn(x) { x * 1.5 }; /* ✓ */ \"naïve\" // end\n", 500)
+
+	expected := New(input)
+	l := NewReader(iotest.OneByteReader(strings.NewReader(input)))
+	for i := 0; ; i++ {
+		want := expected.NextToken()
+		got := l.NextToken()
+		if got.Type != want.Type || got.Literal != want.Literal || got.Pos != want.Pos || got.End != want.End {
+			t.Fatalf("token[%d] wrong. expected=%+v, got=%+v", i, want, got)
+		}
+		if want.Type == token.EOF {
+			break
+		}
+	}
+	if l.Err() != nil {
+		t.Errorf("unexpected read error: %v", l.Err())
+	}
+}
+
+func TestNewReaderError(t *testing.T) {
+	boom := errors.New("boom")
+	l := NewReader(io.MultiReader(strings.NewReader("let x"), iotest.ErrReader(boom)))
+
+	for _, expected := range []token.TokenType{token.LET, token.IDENT, token.EOF} {
+		if tok := l.NextToken(); tok.Type != expected {
+			t.Fatalf("tokentype wrong. expected=%q, got=%q", expected, tok.Type)
+		}
+	}
+	if l.Err() != boom {
+		t.Errorf("Err wrong. expected=%v, got=%v", boom, l.Err())
+	}
+}
+
+func TestNewReaderSkipsTrivia(t *testing.T) {
+	input := "/* " + strings.Repeat("a long comment ", 10000) + "*/ x"
+
+	l := NewReader(strings.NewReader(input))
+	if tok := l.NextToken(); tok.Type != token.IDENT {
+		t.Fatalf("tokentype wrong. expected=%q, got=%q", token.IDENT, tok.Type)
+	}
+	if cap(l.text) > 64 {
+		t.Errorf("comment text recorded without KeepTrivia. got cap=%d", cap(l.text))
+	}
+}
+
+func TestNulCharacter(t *testing.T) {
+	input := "a \x00 b \"c\x00d\" `e\x00f`"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.ILLEGAL, "\x00"},
+		{token.IDENT, "b"},
+		{token.STRING, "c\x00d"},
+		{token.STRING, "e\x00f"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - token wrong. expected=%q %q, got=%q %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	input := "let a = 1 @ 2;\nlet b = \"x\\q\" & 0b2;"
+
+	expected := []Diagnostic{
+		{Pos: token.Position{Offset: 10, Line: 1, Column: 11}, End: token.Position{Offset: 11, Line: 1, Column: 12},
+			Char: "@", Reason: "unexpected character '@'"},
+		{Pos: token.Position{Offset: 25, Line: 2, Column: 11}, End: token.Position{Offset: 27, Line: 2, Column: 13},
+			Char: "\\q", Reason: "unknown escape sequence \\q"},
+		{Pos: token.Position{Offset: 29, Line: 2, Column: 15}, End: token.Position{Offset: 30, Line: 2, Column: 16},
+			Char: "&", Reason: "unexpected character '&'"},
+		{Pos: token.Position{Offset: 33, Line: 2, Column: 19}, End: token.Position{Offset: 34, Line: 2, Column: 20},
+			Char: "2", Reason: "invalid digit '2' in binary literal"},
+	}
+
+	l := New(input)
+	illegal := 0
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Type == token.ILLEGAL {
+			if tok.Literal != expected[illegal].Char {
+				t.Errorf("illegal[%d] literal wrong. expected=%q, got=%q",
+					illegal, expected[illegal].Char, tok.Literal)
+			}
+			illegal++
+		}
+	}
+	diags := l.Diagnostics()
+	if illegal != len(expected) || len(diags) != len(expected) {
+		t.Fatalf("wrong number of diagnostics. got=%d illegal tokens, %v", illegal, diags)
+	}
+	for i, d := range expected {
+		if diags[i] != d {
+			t.Errorf("diagnostics[%d] wrong. expected=%+v, got=%+v", i, d, diags[i])
+		}
+	}
+}
+
+func TestInterpolatedStrings(t *testing.T) {
+	input := `"a ${x} b ${f({1: "${y}"})} c" "\${x} $5"`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INTERP_HEAD, "a "},
+		{token.IDENT, "x"},
+		{token.INTERP_MID, " b "},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.LBRACE, "{"},
+		{token.INT, "1"},
+		{token.COLON, ":"},
+		{token.INTERP_HEAD, ""},
+		{token.IDENT, "y"},
+		{token.INTERP_TAIL, ""},
+		{token.RBRACE, "}"},
+		{token.RPAREN, ")"},
+		{token.INTERP_TAIL, " c"},
+		{token.STRING, "${x} $5"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+	testDiagnostic(t, 0, l, "")
+}
+
+func TestAssignmentTokens(t *testing.T) {
+	input := "x = 1; x += 2; x -= 3; x *= 4; x /= 5 // done"
+
+	expected := []token.TokenType{
+		token.IDENT, token.ASSIGN, token.INT, token.SEMICOLON,
+		token.IDENT, token.PLUS_ASSIGN, token.INT, token.SEMICOLON,
+		token.IDENT, token.MINUS_ASSIGN, token.INT, token.SEMICOLON,
+		token.IDENT, token.ASTERISK_ASSIGN, token.INT, token.SEMICOLON,
+		token.IDENT, token.SLASH_ASSIGN, token.INT, token.EOF,
+	}
+
+	l := New(input)
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestMatchTokens(t *testing.T) {
+	input := "match (x) { [a, ...rest] => a, _ => 0 } . .."
+
+	expected := []token.TokenType{
+		token.MATCH, token.LPAREN, token.IDENT, token.RPAREN, token.LBRACE,
+		token.LBRACKET, token.IDENT, token.COMMA, token.ELLIPSIS, token.IDENT, token.RBRACKET,
+		token.ARROW, token.IDENT, token.COMMA, token.IDENT, token.ARROW, token.INT, token.RBRACE,
+		token.DOT, token.DOT, token.DOT, token.EOF,
+	}
+
+	l := New(input)
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestPipeAndDotTokens(t *testing.T) {
+	input := "xs.map(f) |> g || 1.5.x"
+
+	expected := []token.TokenType{
+		token.IDENT, token.DOT, token.IDENT, token.LPAREN, token.IDENT, token.RPAREN,
+		token.PIPE, token.IDENT, token.OR, token.FLOAT, token.DOT, token.IDENT, token.EOF,
+	}
+
+	l := New(input)
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestExceptionTokens(t *testing.T) {
+	input := "try { throw e } catch (err) { } finally { }"
+
+	expected := []token.TokenType{
+		token.TRY, token.LBRACE, token.THROW, token.IDENT, token.RBRACE,
+		token.CATCH, token.LPAREN, token.IDENT, token.RPAREN, token.LBRACE, token.RBRACE,
+		token.FINALLY, token.LBRACE, token.RBRACE, token.EOF,
+	}
+
+	l := New(input)
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+}
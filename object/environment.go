@@ -1,5 +1,14 @@
 package object
 
+import "OSPLang/token"
+
+//Importer loads the module that an import statement names. from is the
+//position of the import, whose file name relative paths start from. It
+//returns a *Module or an *Error.
+type Importer interface {
+	Import(path string, from token.Position) Object
+}
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
@@ -11,8 +20,9 @@ func NewEnvironment() *Environment {
 }
 
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store    map[string]Object
+	outer    *Environment
+	importer Importer
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -26,3 +36,32 @@ func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
 	return val
 }
+
+//Assign updates name in the nearest environment that binds it, and
+//reports whether there was one
+func (e *Environment) Assign(name string, val Object) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			env.store[name] = val
+			return true
+		}
+	}
+	return false
+}
+
+//SetImporter makes imp load the modules imported in e and the
+//environments enclosed by it
+func (e *Environment) SetImporter(imp Importer) {
+	e.importer = imp
+}
+
+//Importer returns the importer of the nearest environment that has one,
+//or nil
+func (e *Environment) Importer() Importer {
+	for env := e; env != nil; env = env.outer {
+		if env.importer != nil {
+			return env.importer
+		}
+	}
+	return nil
+}
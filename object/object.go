@@ -2,9 +2,11 @@ package object
 
 import (
 	"OSPLang/ast"
+	"OSPLang/token"
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"strconv"
 	"strings"
 )
 
@@ -19,6 +21,7 @@ type Object interface {
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
@@ -27,6 +30,28 @@ const (
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
 	HASH_OBJ         = "HASH"
+	ARRAY_OBJ        = "ARRAY"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
+	QUOTE_OBJ        = "QUOTE"
+	MACRO_OBJ        = "MACRO"
+	MODULE_OBJ       = "MODULE"
+	ERROR_VALUE_OBJ  = "ERROR_VALUE"
+	STRUCT_OBJ       = "STRUCT"
+	INSTANCE_OBJ     = "INSTANCE"
+)
+
+//Kinds of Error. The interpreter raises errors of these kinds; a script
+//can throw errors of any kind.
+const (
+	GENERIC_ERROR    = "Error"
+	TYPE_ERROR       = "TypeError"
+	NAME_ERROR       = "NameError"
+	ARGUMENT_ERROR   = "ArgumentError"
+	INDEX_ERROR      = "IndexError"
+	ARITHMETIC_ERROR = "ArithmeticError"
+	MATCH_ERROR      = "MatchError"
+	IMPORT_ERROR     = "ImportError"
 )
 
 //Integer struct with Value int64
@@ -40,6 +65,24 @@ func (i *Integer) Inspect() string { return fmt.Sprintf("%d", i.Value) }
 //Type is of Integer
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 
+//Float struct with Value float64
+type Float struct {
+	Value float64
+}
+
+//Inspect is of Float; integral values keep a trailing .0 so they read
+//back as floats
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eEnN") {
+		s += ".0"
+	}
+	return s
+}
+
+//Type is of Float
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
 //Boolean struct with bool
 type Boolean struct {
 	Value bool
@@ -71,16 +114,64 @@ func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 //Inspect is of ReturnValue
 func (rv *ReturnValue) Inspect() string { return rv.Value.Inspect() }
 
-//Error is
+//Break is the signal a break statement sends to the enclosing loop
+type Break struct{}
+
+//Type is of Break
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+
+//Inspect is of Break
+func (b *Break) Inspect() string { return "break" }
+
+//Continue is the signal a continue statement sends to the enclosing loop
+type Continue struct{}
+
+//Type is of Continue
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+
+//Inspect is of Continue
+func (c *Continue) Inspect() string { return "continue" }
+
+//Error is an error being raised: it unwinds evaluation up to the
+//innermost try with a catch, or to the top. Cause is the error that led
+//to it, if any.
 type Error struct {
 	Message string
+	Kind    string
+	Cause   *Error
+	Pos     token.Position // where in the source the error was raised
 }
 
 //Type is of Error
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 
 //Inspect is of Error
-func (e *Error) Inspect() string { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string {
+	var out bytes.Buffer
+	out.WriteString("ERROR: ")
+	if e.Pos.IsValid() {
+		out.WriteString(e.Pos.String() + ": ")
+	}
+	out.WriteString(e.Message)
+	if e.Cause != nil {
+		out.WriteString("\ncaused by " + e.Cause.Inspect())
+	}
+	return out.String()
+}
+
+//ErrorValue is an error held as a value, as caught by a catch or made by
+//the error builtin. Throwing it raises Error again.
+type ErrorValue struct {
+	Error *Error
+}
+
+//Type is of ErrorValue
+func (ev *ErrorValue) Type() ObjectType { return ERROR_VALUE_OBJ }
+
+//Inspect is of ErrorValue
+func (ev *ErrorValue) Inspect() string {
+	return ev.Error.Kind + ": " + ev.Error.Message
+}
 
 /*
 //NewEnvironment is ...
@@ -110,6 +201,8 @@ func (e *Environment) Set(name string, val Object) Object {
 
 type Function struct {
 	Parameters []*ast.Identifier
+	Defaults   []ast.Expression // parallel to Parameters, nil where there is no default
+	Rest       *ast.Identifier  // the ...rest parameter, or nil
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -119,8 +212,15 @@ func (f *Function) Inspect() string {
 
 	var out bytes.Buffer
 	params := []string{}
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
+	for i, p := range f.Parameters {
+		if i < len(f.Defaults) && f.Defaults[i] != nil {
+			params = append(params, p.String()+" = "+f.Defaults[i].String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.String())
 	}
 
 	out.WriteString("fn")
@@ -132,6 +232,80 @@ func (f *Function) Inspect() string {
 	return out.String()
 }
 
+//Quote holds unevaluated code, as made by quote(...). Unquoted lists the
+//subtrees of Node that unquote(...) spliced in.
+type Quote struct {
+	Node     ast.Node
+	Unquoted []ast.Node
+}
+
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (q *Quote) Inspect() string {
+	return "QUOTE(" + q.Node.String() + ")"
+}
+
+//Macro is a macro defined with a top-level let
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+func (m *Macro) Inspect() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("macro")
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(m.Body.String())
+	out.WriteString("\n}")
+	return out.String()
+}
+
+//Module is a source file evaluated by an import. Exports holds the values
+//of its export lets, by name; Names lists them in source order.
+type Module struct {
+	Name    string // the canonical path of the file
+	Exports map[string]Object
+	Names   []string
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string {
+	return "module " + m.Name + " (" + strings.Join(m.Names, ", ") + ")"
+}
+
+//Struct is a type declared with struct. Calling it makes an Instance.
+type Struct struct {
+	Name    string
+	Fields  []string
+	Methods map[string]*Function
+}
+
+func (s *Struct) Type() ObjectType { return STRUCT_OBJ }
+func (s *Struct) Inspect() string {
+	if len(s.Fields) == 0 {
+		return "struct " + s.Name + " {}"
+	}
+	return "struct " + s.Name + " { " + strings.Join(s.Fields, ", ") + " }"
+}
+
+//Instance is a value of a Struct. Fields holds a value for each of the
+//fields of its Struct, and for no other name.
+type Instance struct {
+	Struct *Struct
+	Fields map[string]Object
+}
+
+func (i *Instance) Type() ObjectType { return INSTANCE_OBJ }
+func (i *Instance) Inspect() string  { return inspect(i, map[Object]bool{}) }
+
 type String struct {
 	Value string
 }
@@ -148,6 +322,14 @@ type Builtin struct {
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
 func (b *Builtin) Inspect() string  { return "builtin function" }
 
+//Array is an ordered list of values
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+func (a *Array) Inspect() string  { return inspect(a, map[Object]bool{}) }
+
 type HashKey struct {
 	Type  ObjectType
 	Value uint64
@@ -179,27 +361,115 @@ type HashPair struct {
 	Value Object
 }
 
+//Hash maps keys to values, keeping its keys in insertion order. It is
+//changed through Set and read through Get and Ordered.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	pairs map[HashKey]HashPair
+	keys  []HashKey // the keys of pairs in insertion order
 }
 
-func (h *Hash) Type() ObjectType { return HASH_OBJ }
-
-func (h *Hash) Inspect() string {
-	var out bytes.Buffer
-	pairs := []string{}
+//NewHash returns an empty Hash
+func NewHash() *Hash {
+	return &Hash{pairs: make(map[HashKey]HashPair)}
+}
 
-	for _, pair := range h.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+//Set stores pair under key. A new key goes after the existing ones; a
+//replaced one keeps its place.
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	if _, ok := h.pairs[key]; !ok {
+		h.keys = append(h.keys, key)
 	}
+	h.pairs[key] = pair
+}
 
-	out.WriteString("{")
-	out.WriteString(strings.Join(pairs, ", "))
-	out.WriteString("}")
+//Get returns the pair stored under key and whether there is one
+func (h *Hash) Get(key HashKey) (HashPair, bool) {
+	pair, ok := h.pairs[key]
+	return pair, ok
+}
 
-	return out.String()
+//Len is the number of pairs in h
+func (h *Hash) Len() int {
+	return len(h.keys)
+}
+
+//Ordered returns the pairs in insertion order
+func (h *Hash) Ordered() []HashPair {
+	pairs := make([]HashPair, 0, len(h.keys))
+	for _, key := range h.keys {
+		pairs = append(pairs, h.pairs[key])
+	}
+	return pairs
 }
 
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+
+func (h *Hash) Inspect() string { return inspect(h, map[Object]bool{}) }
+
 type Hashable interface {
 	HashKey() HashKey
 }
+
+//inspect is obj.Inspect(), except that an array, hash or instance that
+//holds itself, directly or not, is shown as [...], {...} or Name{...}
+//where it recurs.
+//seen holds the values being shown.
+func inspect(obj Object, seen map[Object]bool) string {
+	var out bytes.Buffer
+
+	switch obj := obj.(type) {
+	case *Array:
+		if seen[obj] {
+			return "[...]"
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+
+		elements := []string{}
+		for _, e := range obj.Elements {
+			elements = append(elements, inspect(e, seen))
+		}
+
+		out.WriteString("[")
+		out.WriteString(strings.Join(elements, ", "))
+		out.WriteString("]")
+
+	case *Hash:
+		if seen[obj] {
+			return "{...}"
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+
+		pairs := []string{}
+		for _, pair := range obj.Ordered() {
+			pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), inspect(pair.Value, seen)))
+		}
+
+		out.WriteString("{")
+		out.WriteString(strings.Join(pairs, ", "))
+		out.WriteString("}")
+
+	case *Instance:
+		if seen[obj] {
+			return obj.Struct.Name + "{...}"
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+
+		fields := []string{}
+		for _, name := range obj.Struct.Fields {
+			fields = append(fields, name+": "+inspect(obj.Fields[name], seen))
+		}
+
+		out.WriteString(obj.Struct.Name)
+		out.WriteString("{")
+		out.WriteString(strings.Join(fields, ", "))
+		out.WriteString("}")
+
+	default:
+		return obj.Inspect()
+	}
+
+	return out.String()
+}
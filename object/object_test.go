@@ -20,3 +20,54 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("String with differnent content havev same hash key")
 	}
 }
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{3.14, "3.14"},
+		{3, "3.0"},
+		{-0.5, "-0.5"},
+		{1e21, "1e+21"},
+	}
+
+	for _, tt := range tests {
+		if got := (&Float{Value: tt.value}).Inspect(); got != tt.expected {
+			t.Errorf("Float.Inspect wrong. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+}
+
+func TestHashOrder(t *testing.T) {
+	h := NewHash()
+	for _, k := range []string{"b", "a", "c"} {
+		key := &String{Value: k}
+		h.Set(key.HashKey(), HashPair{Key: key, Value: &Integer{Value: 1}})
+	}
+	a := &String{Value: "a"}
+	h.Set(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 2}})
+
+	if h.Inspect() != "{b: 1, a: 2, c: 1}" {
+		t.Errorf("h.Inspect() wrong. got=%q", h.Inspect())
+	}
+	if len(h.keys) != 3 || len(h.pairs) != 3 || h.Len() != 3 {
+		t.Errorf("wrong number of keys. got=%d keys, %d pairs", len(h.keys), len(h.pairs))
+	}
+}
+
+func TestInspectCycles(t *testing.T) {
+	a := &Array{Elements: []Object{&Integer{Value: 0}}}
+	a.Elements[0] = a
+	if a.Inspect() != "[[...]]" {
+		t.Errorf("a.Inspect() wrong. got=%q", a.Inspect())
+	}
+
+	h := NewHash()
+	key := &String{Value: "self"}
+	h.Set(key.HashKey(), HashPair{Key: key, Value: h})
+	b := &Array{Elements: []Object{h, h}}
+	if b.Inspect() != "[{self: {...}}, {self: {...}}]" {
+		t.Errorf("b.Inspect() wrong. got=%q", b.Inspect())
+	}
+}
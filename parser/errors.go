@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"OSPLang/lexer"
+	"OSPLang/token"
+	"sort"
+)
+
+//ErrorCode classifies a ParseError
+type ErrorCode string
+
+const (
+	//ErrLexical is a problem the lexer found in the source text
+	ErrLexical ErrorCode = "lexical"
+	//ErrUnexpectedToken is a token other than the ones the grammar allows
+	ErrUnexpectedToken ErrorCode = "unexpected-token"
+	//ErrNoExpression is a token that cannot start an expression
+	ErrNoExpression ErrorCode = "no-expression"
+	//ErrInvalidNumber is a number literal out of range
+	ErrInvalidNumber ErrorCode = "invalid-number"
+	//ErrTooDeep is an expression nested past maxNesting
+	ErrTooDeep ErrorCode = "too-deep"
+	//ErrOutsideLoop is a break or continue that is not in a loop body
+	ErrOutsideLoop ErrorCode = "outside-loop"
+	//ErrInvalidAssignment is an assignment to something other than a
+	//name, an index expression or a member expression
+	ErrInvalidAssignment ErrorCode = "invalid-assignment"
+	//ErrInvalidPattern is a token that cannot start a pattern or a hash
+	//pattern key
+	ErrInvalidPattern ErrorCode = "invalid-pattern"
+	//ErrInvalidArgument is a positional argument after a named one
+	ErrInvalidArgument ErrorCode = "invalid-argument"
+	//ErrMisplacedExport is an export inside a block
+	ErrMisplacedExport ErrorCode = "misplaced-export"
+	//ErrDuplicateMember is a struct field or method whose name is taken
+	ErrDuplicateMember ErrorCode = "duplicate-member"
+)
+
+//ParseError is a syntax error in the source. Pos and End span the
+//offending token, Got, and Expected lists the token types that would
+//have been accepted in its place, if the grammar allows only some.
+type ParseError struct {
+	Pos      token.Position
+	End      token.Position
+	Code     ErrorCode
+	Expected []token.TokenType
+	Got      token.Token
+	Msg      string
+}
+
+//Error returns the message prefixed with the position, as in
+//"main.osp:3:7: expected next token to be =, got INT instead"
+func (e ParseError) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+//lexicalError turns a lexer diagnostic into a ParseError
+func lexicalError(d lexer.Diagnostic) ParseError {
+	return ParseError{
+		Pos:  d.Pos,
+		End:  d.End,
+		Code: ErrLexical,
+		Got:  token.Token{Type: token.ILLEGAL, Literal: d.Char, Pos: d.Pos, End: d.End},
+		Msg:  d.Reason,
+	}
+}
+
+//expressionStarts lists the token types that have a prefix parse
+//function, in a stable order
+func (p *Parser) expressionStarts() []token.TokenType {
+	types := make([]token.TokenType, 0, len(p.prefixParseFns))
+	for t := range p.prefixParseFns {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
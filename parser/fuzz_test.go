@@ -0,0 +1,36 @@
+package parser
+
+import (
+	"OSPLang/lexer"
+	"testing"
+)
+
+func FuzzParseProgram(f *testing.F) {
+	seeds := []string{
+		"let x = 5; return x;",
+		"return 5",
+		"let add = fn(a, b) { a + b }; add(1, 2 * 3)[0];",
+		`if (x <= 1 && y) { "a ${b} c" } else { {"k": [1, 2.5]} }`,
+		"let = ; fn(1) { } ((( [ {",
+		"/* unterminated",
+		"\"${",
+		"}}}; ]) let",
+		"while (x) { for (k, v in y) { break; continue } } break",
+		"-(1 +) = 2",
+		"!$ = 0",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+		for i, stmt := range program.Statements {
+			if stmt == nil {
+				t.Fatalf("program.Statements[%d] is nil", i)
+			}
+		}
+		_ = program.String()
+	})
+}
@@ -11,20 +11,35 @@ import (
 const (
 	_int = iota
 	LOWEST
+	ASSIGN
+	PIPE
+	OR
+	AND
 	EQUALS
 	LESSGREATER
 	SUM
 	PRODUCT
 	PREFIX
 	CALL
+	INDEX
 )
 
+//maxNesting bounds how deeply expressions may nest, so that hostile input
+//cannot exhaust the Go stack
+const maxNesting = 1000
+
 //Parser is ...
 type Parser struct {
 	l         *lexer.Lexer
 	curToken  token.Token
 	peekToken token.Token
-	errors    []string
+	errors    []ParseError
+
+	failures   int  // errors met so far, including ILLEGAL tokens
+	recovering bool // an error was met and the statement is being abandoned
+	blocks     int  // how many blocks enclose the current token
+	loops      int  // how many loop bodies enclose the current token
+	nesting    int  // depth of parseExpression calls
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
@@ -37,7 +52,7 @@ type (
 
 //New is ...
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, errors: []ParseError{}}
 
 	p.nextToken()
 	p.nextToken()
@@ -45,6 +60,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
@@ -56,14 +72,31 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.INTERP_HEAD, p.parseInterpolatedString)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
+	p.registerInfix(token.PIPE, p.parsePipeExpression)
+	p.registerInfix(token.DOT, p.parseDotExpression)
 	return p
 }
 
@@ -99,12 +132,39 @@ func (p *Parser) ParseProgram() *ast.Program {
 
 }
 
+//parseStatement parses one statement. A statement in which an error is
+//met is dropped, and the parser skips to the next statement boundary
+//so that later, independent errors are reported too.
 func (p *Parser) parseStatement() ast.Statement {
+	failures := p.failures
+	stmt := p.parseStatementKind()
+	if p.failures > failures {
+		p.synchronize()
+		return nil
+	}
+	return stmt
+}
+
+func (p *Parser) parseStatementKind() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForInStatement()
+	case token.BREAK, token.CONTINUE:
+		return p.parseLoopControl()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.STRUCT:
+		return p.parseStructStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -112,10 +172,18 @@ func (p *Parser) parseStatement() ast.Statement {
 
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
-	if !p.expectPeek(token.IDENT) {
-		return nil
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		stmt.Pattern = p.parsePattern()
+		if stmt.Pattern == nil {
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	}
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -127,6 +195,100 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	return stmt
 }
 
+//parseImportStatement parses import "path" as name
+func (p *Parser) parseImportStatement() ast.Statement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+	stmt.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.AS) || !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+//parseExportStatement parses export let ..., which is only allowed at
+//the top level of a program
+func (p *Parser) parseExportStatement() ast.Statement {
+	stmt := &ast.ExportStatement{Token: p.curToken}
+	if p.blocks > 0 {
+		p.addError(ErrMisplacedExport, p.curToken, nil, "export outside the top level")
+		return nil
+	}
+	if !p.expectPeek(token.LET) {
+		return nil
+	}
+	stmt.Statement = p.parseLetStatement()
+	if stmt.Statement == nil {
+		return nil
+	}
+	return stmt
+}
+
+//synchronize skips the rest of a statement that failed to parse. It stops
+//on the statement's closing semicolon, before a keyword that starts a new
+//statement or before the } that closes the enclosing block.
+func (p *Parser) synchronize() {
+	depth := 0
+	for !p.peekTokenIs(token.EOF) {
+		if depth == 0 {
+			if p.curTokenIs(token.SEMICOLON) || statementStart[p.peekToken.Type] {
+				break
+			}
+			if p.peekTokenIs(token.RBRACE) && p.blocks > 0 {
+				break
+			}
+		}
+		p.nextToken()
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+	p.recovering = false
+}
+
+//statementStart holds the tokens that can only begin a statement
+var statementStart = map[token.TokenType]bool{
+	token.LET:      true,
+	token.RETURN:   true,
+	token.WHILE:    true,
+	token.FOR:      true,
+	token.BREAK:    true,
+	token.CONTINUE: true,
+	token.IMPORT:   true,
+	token.EXPORT:   true,
+	token.THROW:    true,
+	token.STRUCT:   true,
+}
+
+//addError records an error about the token got. Only the first error of
+//a statement is recorded: the ones that follow are usually caused by it.
+func (p *Parser) addError(code ErrorCode, got token.Token, expected []token.TokenType, format string, a ...interface{}) {
+	if p.recovering {
+		return
+	}
+	p.recovering = true
+	p.failures++
+	p.errors = append(p.errors, ParseError{
+		Pos:      got.Pos,
+		End:      got.End,
+		Code:     code,
+		Expected: expected,
+		Got:      got,
+		Msg:      fmt.Sprintf(format, a...),
+	})
+}
+
 func (p *Parser) curTokenIs(t token.TokenType) bool {
 	return p.curToken.Type == t
 }
@@ -143,21 +305,193 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 
 }
 
-//Errors is ...
+//Errors returns the messages of ParseErrors
 func (p *Parser) Errors() []string {
-	return p.errors
+	errors := []string{}
+	for _, e := range p.ParseErrors() {
+		errors = append(errors, e.Error())
+	}
+	return errors
+}
+
+//ParseErrors returns the lexer's diagnostics followed by the parser's
+//own errors
+func (p *Parser) ParseErrors() []ParseError {
+	errors := []ParseError{}
+	for _, d := range p.l.Diagnostics() {
+		errors = append(errors, lexicalError(d))
+	}
+	return append(errors, p.errors...)
 }
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
-		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(ErrUnexpectedToken, p.peekToken, []token.TokenType{t},
+		"expected next token to be %s, got %s instead", t, p.peekToken.Type)
 }
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 	p.nextToken()
 	stmt.ReturnValue = p.parseExpression(LOWEST)
-	for !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+//parseStructStatement parses struct Name { members }, where the members
+//are field names and fn name(params) { body } methods, optionally
+//separated by commas or semicolons
+func (p *Parser) parseStructStatement() ast.Statement {
+	stmt := &ast.StructStatement{Token: p.curToken}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	members := map[string]bool{}
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		var name *ast.Identifier
+		switch p.curToken.Type {
+		case token.IDENT:
+			name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			stmt.Fields = append(stmt.Fields, name)
+		case token.FUNCTION:
+			method := p.parseStructMethod()
+			if method == nil {
+				return nil
+			}
+			name = method.Name
+			stmt.Methods = append(stmt.Methods, method)
+		default:
+			p.addError(ErrUnexpectedToken, p.curToken, []token.TokenType{token.IDENT, token.FUNCTION},
+				"expected a field or method, got %s instead", p.curToken.Type)
+			return nil
+		}
+		if members[name.Value] {
+			p.addError(ErrDuplicateMember, name.Token, nil, "duplicate member %s in struct %s", name.Value, stmt.Name.Value)
+			return nil
+		}
+		members[name.Value] = true
+		if p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+	}
+	p.nextToken()
+	stmt.Rbrace = p.curToken.Pos
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+//parseStructMethod parses fn name(params) { body } in a struct
+func (p *Parser) parseStructMethod() *ast.StructMethod {
+	lit := &ast.FunctionLiteral{Token: p.curToken}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	method := &ast.StructMethod{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}, Function: lit}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	if !p.parseParameters(lit) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	loops := p.loops
+	p.loops = 0
+	lit.Body = p.parseBlockStatement()
+	p.loops = loops
+	return method
+}
+
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseLoopBody()
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+//parseForInStatement parses for (value in iterable) { ... } and
+//for (key, value in iterable) { ... }
+func (p *Parser) parseForInStatement() *ast.ForInStatement {
+	stmt := &ast.ForInStatement{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) || !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Key = stmt.Value
+		stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseLoopBody()
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loops++
+	defer func() { p.loops-- }()
+	return p.parseBlockStatement()
+}
+
+//parseLoopControl parses break and continue, which are only allowed
+//inside a loop body
+func (p *Parser) parseLoopControl() ast.Statement {
+	var stmt ast.Statement
+	if p.curTokenIs(token.BREAK) {
+		stmt = &ast.BreakStatement{Token: p.curToken}
+	} else {
+		stmt = &ast.ContinueStatement{Token: p.curToken}
+	}
+	if p.loops == 0 {
+		p.addError(ErrOutsideLoop, p.curToken, nil, "%s outside loop", p.curToken.Literal)
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
@@ -184,11 +518,26 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	if t == token.ILLEGAL {
+		// the lexer has already reported why; the statement still fails
+		if !p.recovering {
+			p.recovering = true
+			p.failures++
+		}
+		return
+	}
+	p.addError(ErrNoExpression, p.curToken, p.expressionStarts(),
+		"no prefix parse function for %s found", t)
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+	p.nesting++
+	defer func() { p.nesting-- }()
+	if p.nesting > maxNesting {
+		p.addError(ErrTooDeep, p.curToken, nil, "expression nested too deeply")
+		return nil
+	}
+
 	prefix := p.prefixParseFns[p.curToken.Type]
 
 	if prefix == nil {
@@ -217,8 +566,18 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(ErrInvalidNumber, p.curToken, nil, "could not parse %q as integer", p.curToken.Literal)
+		return nil
+	}
+	lit.Value = value
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		p.addError(ErrInvalidNumber, p.curToken, nil, "could not parse %q as float", p.curToken.Literal)
 		return nil
 	}
 	lit.Value = value
@@ -230,11 +589,24 @@ var precedences = map[token.TokenType]int{
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
 	token.GT:       LESSGREATER,
+	token.LT_EQ:    LESSGREATER,
+	token.GT_EQ:    LESSGREATER,
+	token.AND:      AND,
+	token.OR:       OR,
 	token.PLUS:     SUM,
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
 	token.LPAREN:   CALL,
+	token.LBRACKET: INDEX,
+	token.DOT:      INDEX,
+	token.PIPE:     PIPE,
+
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
 }
 
 func (p *Parser) peekPrecedence() int {
@@ -262,6 +634,32 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+//parseAssignExpression parses an assignment to target. Assignment is
+//right associative, so a = b = 1 assigns 1 to both.
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	if target == nil {
+		return nil
+	}
+	exp := &ast.AssignExpression{
+		Token:    p.curToken,
+		Target:   target,
+		Operator: p.curToken.Literal,
+	}
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression, *ast.MemberExpression:
+	default:
+		// while recovering the target may be missing parts, and the
+		// error would be dropped anyway
+		if !p.recovering {
+			p.addError(ErrInvalidAssignment, p.curToken, nil, "cannot assign to %s", target.String())
+		}
+		return nil
+	}
+	p.nextToken()
+	exp.Value = p.parseExpression(ASSIGN - 1)
+	return exp
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
@@ -301,9 +699,52 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	}
 	return expression
 }
+
+//parseTryExpression parses try { } catch (e) { } finally { }, where the
+//name after catch, and either the catch or the finally, may be left out
+func (p *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{Token: p.curToken}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	expression.Body = p.parseBlockStatement()
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			expression.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		expression.Catch = p.parseBlockStatement()
+	}
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		expression.Finally = p.parseBlockStatement()
+	}
+	if expression.Catch == nil && expression.Finally == nil {
+		p.addError(ErrUnexpectedToken, p.peekToken, []token.TokenType{token.CATCH, token.FINALLY},
+			"expected catch or finally after try block, got %s instead", p.peekToken.Type)
+		return nil
+	}
+	return expression
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
+	p.blocks++
+	defer func() { p.blocks-- }()
 	p.nextToken()
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
 		stmt := p.parseStatement()
@@ -312,6 +753,9 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		}
 		p.nextToken()
 	}
+	if p.curTokenIs(token.RBRACE) {
+		block.Rbrace = p.curToken.Pos
+	}
 	return block
 }
 func (p *Parser) parseFunctionLiteral() ast.Expression {
@@ -319,25 +763,92 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
+	if !p.parseParameters(lit) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	// a loop around the literal is not one around the function's body
+	loops := p.loops
+	p.loops = 0
+	lit.Body = p.parseBlockStatement()
+	p.loops = loops
+	return lit
+}
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	lit := &ast.MacroLiteral{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
 	lit.Parameters = p.parseFunctionParameters()
-	if !p.expectPeek(token.LBRACE) {
+	if lit.Parameters == nil || !p.expectPeek(token.LBRACE) {
 		return nil
 	}
+	loops := p.loops
+	p.loops = 0
 	lit.Body = p.parseBlockStatement()
+	p.loops = loops
 	return lit
 }
+
+//parseParameters parses the parameters of a function literal into lit:
+//names, each with an optional = default, and a last ...rest. It reports
+//whether they parsed.
+func (p *Parser) parseParameters(lit *ast.FunctionLiteral) bool {
+	lit.Parameters = []*ast.Identifier{}
+	lit.Defaults = []ast.Expression{}
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return true
+	}
+	for {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			lit.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			break
+		}
+		if !p.expectPeek(token.IDENT) {
+			return false
+		}
+		lit.Parameters = append(lit.Parameters, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+		var def ast.Expression
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			def = p.parseExpression(LOWEST)
+			if def == nil {
+				return false
+			}
+		}
+		lit.Defaults = append(lit.Defaults, def)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	return p.expectPeek(token.RPAREN)
+}
+
+//parseFunctionParameters parses the plain parameter names of a macro
 func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	identifiers := []*ast.Identifier{}
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
 		return identifiers
 	}
-	p.nextToken()
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
 	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	identifiers = append(identifiers, ident)
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
 		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 		identifiers = append(identifiers, ident)
 	}
@@ -350,9 +861,45 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
 	exp.Arguments = p.parseCallArguments()
+	if exp.Arguments != nil {
+		exp.Rparen = p.curToken.Pos
+	}
 	return exp
 }
 
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	exp := &ast.PipeExpression{Token: p.curToken, Left: left}
+	p.nextToken()
+	exp.Right = p.parseExpression(PIPE)
+	return exp
+}
+
+//parseDotExpression parses .name(arguments) after a receiver, a method
+//call, or just .name, a member of it
+func (p *Parser) parseDotExpression(receiver ast.Expression) ast.Expression {
+	dot := p.curToken
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.peekTokenIs(token.LPAREN) {
+		return &ast.MemberExpression{Token: dot, Object: receiver, Name: name}
+	}
+	p.nextToken()
+
+	exp := &ast.MethodCallExpression{Token: dot, Receiver: receiver, Method: name}
+	exp.Arguments = p.parseCallArguments()
+	if exp.Arguments == nil {
+		return nil
+	}
+	exp.Rparen = p.curToken.Pos
+	return exp
+}
+
+//parseCallArguments parses the arguments of a call up to the closing ),
+//which becomes the current token. An argument written name: value is an
+//ast.NamedArgument; named arguments come after the positional ones. It
+//returns nil if the ) is missing.
 func (p *Parser) parseCallArguments() []ast.Expression {
 	args := []ast.Expression{}
 
@@ -361,29 +908,114 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 		return args
 	}
 
+	named := false
+	for {
+		p.nextToken()
+		if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON) {
+			arg := &ast.NamedArgument{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+			p.nextToken()
+			p.nextToken()
+			arg.Value = p.parseExpression(LOWEST)
+			args = append(args, arg)
+			named = true
+		} else if named {
+			p.addError(ErrInvalidArgument, p.curToken, nil, "positional argument after named argument")
+			return nil
+		} else {
+			args = append(args, p.parseExpression(LOWEST))
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return args
+}
+
+//parseExpressionList parses comma separated expressions up to the end
+//token, which becomes the current token. It returns nil if end is missing.
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return list
+	}
+
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	list = append(list, p.parseExpression(LOWEST))
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		list = append(list, p.parseExpression(LOWEST))
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectPeek(end) {
 		return nil
 	}
-	return args
+	return list
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if array.Elements == nil {
+		return nil
+	}
+	array.Rbracket = p.curToken.Pos
+	return array
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	exp.Rbracket = p.curToken.Pos
+	return exp
 }
 
 func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+//parseInterpolatedString parses the parts of a string with embedded
+//expressions, from its INTERP_HEAD up to its INTERP_TAIL
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken}
+	str.Parts = []ast.Expression{p.parseStringLiteral()}
+	for {
+		p.nextToken()
+		exp := p.parseExpression(LOWEST)
+		if exp == nil {
+			return nil
+		}
+		str.Parts = append(str.Parts, exp)
+		if !p.peekTokenIs(token.INTERP_MID) && !p.peekTokenIs(token.INTERP_TAIL) {
+			p.addError(ErrUnexpectedToken, p.peekToken,
+				[]token.TokenType{token.INTERP_MID, token.INTERP_TAIL},
+				"expected } to close embedded expression, got %s instead", p.peekToken.Type)
+			return nil
+		}
+		p.nextToken()
+		str.Parts = append(str.Parts, p.parseStringLiteral())
+		if p.curTokenIs(token.INTERP_TAIL) {
+			return str
+		}
+	}
+}
+
 func (p *Parser) parseHashLiteral() ast.Expression {
 
 	hash := &ast.HashLiteral{Token: p.curToken}
-	hash.Pairs = make(map[ast.Expression]ast.Expression)
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
@@ -395,7 +1027,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
-		hash.Pairs[key] = value
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -406,6 +1038,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	if !p.expectPeek(token.RBRACE) {
 		return nil
 	}
+	hash.Rbrace = p.curToken.Pos
 
 	return hash
 
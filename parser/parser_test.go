@@ -3,7 +3,9 @@ package parser
 import (
 	"OSPLang/ast"
 	"OSPLang/lexer"
+	"OSPLang/token"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -368,6 +370,18 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a + b + c * d / f + g)",
 			"add((((a + b) + ((c * d) / f)) + g))",
 		},
+		{
+			"a * [1, 2, 3, 4][b * c] * d",
+			"((a * ([1, 2, 3, 4][(b * c)])) * d)",
+		},
+		{
+			"add(a * b[2], b[1], 2 * [1, 2][1])",
+			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
+		},
+		{
+			"f(x)[0][1]",
+			"((f(x)[0])[1])",
+		},
 	}
 	for _, tt := range tests {
 		l := lexer.New(tt.input)
@@ -666,15 +680,15 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		"three": 3,
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
 
-		literal, ok := key.(*ast.StringLiteral)
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
 		}
 
 		expectedValue := expected[literal.String()]
-		testIntegerLiteral(t, value, expectedValue)
+		testIntegerLiteral(t, pair.Value, expectedValue)
 
 	}
 
@@ -721,10 +735,10 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 			testInfixExpression(t, e, 15, "/", 5)
 		},
 	}
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
 			continue
 		}
 		testFunc, ok := tests[literal.String()]
@@ -732,6 +746,846 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 			t.Errorf("No test function for key %q found", literal.String())
 			continue
 		}
-		testFunc(value)
+		testFunc(pair.Value)
+	}
+}
+
+func TestNodeSpans(t *testing.T) {
+	input := "let add = fn(x, y) {\n  x + y;\n};\nadd(1, 2)"
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	tests := []struct {
+		node     ast.Node
+		pos, end string
+	}{
+		{program.Statements[0], "1:1", "3:2"},
+		{program.Statements[0].(*ast.LetStatement).Value, "1:11", "3:2"},
+		{program.Statements[1], "4:1", "4:10"},
+	}
+	for i, tt := range tests {
+		if got := tt.node.Pos().String(); got != tt.pos {
+			t.Errorf("tests[%d] - pos wrong. expected=%s, got=%s", i, tt.pos, got)
+		}
+		if got := tt.node.End().String(); got != tt.end {
+			t.Errorf("tests[%d] - end wrong. expected=%s, got=%s", i, tt.end, got)
+		}
+	}
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	input := "let x 5;\nlet = 10;"
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	expected := []string{
+		"1:7: expected next token to be =, got INT instead",
+		"2:5: expected next token to be IDENT, got = instead",
+	}
+	errors := p.Errors()
+	if len(errors) != len(expected) {
+		t.Fatalf("wrong number of errors. got=%d (%q)", len(errors), errors)
+	}
+	for i, msg := range expected {
+		if errors[i] != msg {
+			t.Errorf("errors[%d] wrong. expected=%q, got=%q", i, msg, errors[i])
+		}
+	}
+}
+
+func TestStringLiteralEscapes(t *testing.T) {
+	input := `"tab\there \u{2764}";`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value != "tab\there ❤" {
+		t.Errorf("literal.Value wrong. got=%q", literal.Value)
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	input := "2.5e-1;"
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.FloatLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value != 0.25 {
+		t.Errorf("literal.Value not %f. got=%f", 0.25, literal.Value)
+	}
+	if literal.TokenLiteral() != "2.5e-1" {
+		t.Errorf("literal.TokenLiteral not %s. got=%s", "2.5e-1", literal.TokenLiteral())
+	}
+}
+
+func TestLogicalOperatorPrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a <= b", "(a <= b)"},
+		{"a >= b == true", "((a >= b) == true)"},
+		{"a && b || c", "((a && b) || c)"},
+		{"a || b && c", "(a || (b && c))"},
+		{"a < b && b <= c", "((a < b) && (b <= c))"},
+		{"a == b || !c", "((a == b) || (!c))"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestLexerDiagnosticsComeFirst(t *testing.T) {
+	input := "let x = 5 @;\nlet = 1;"
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	expected := []string{
+		"1:11: unexpected character '@'",
+		"2:5: expected next token to be IDENT, got = instead",
+	}
+	errors := p.Errors()
+	if len(errors) != len(expected) {
+		t.Fatalf("wrong number of errors. got=%d (%q)", len(errors), errors)
+	}
+	for i, msg := range expected {
+		if errors[i] != msg {
+			t.Errorf("errors[%d] wrong. expected=%q, got=%q", i, msg, errors[i])
+		}
+	}
+}
+
+func TestInterpolatedStringParsing(t *testing.T) {
+	input := `"Hello ${name}, you have ${len(items) + 1} items"`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	str, ok := stmt.Expression.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+	}
+	if len(str.Parts) != 5 {
+		t.Fatalf("wrong number of parts. got=%d", len(str.Parts))
+	}
+	testIdentifier(t, str.Parts[1], "name")
+	if lit := str.Parts[4].(*ast.StringLiteral); lit.Value != " items" {
+		t.Errorf("last part wrong. got=%q", lit.Value)
+	}
+	if str.String() != `"Hello ${name}, you have ${(len(items) + 1)} items"` {
+		t.Errorf("str.String() wrong. got=%q", str.String())
+	}
+	if end := str.End(); end.Offset != len(input) {
+		t.Errorf("str.End() wrong. got=%d", end.Offset)
+	}
+}
+
+func TestInterpolatedStringErrors(t *testing.T) {
+	l := lexer.New(`"a ${x y} b"`)
+	p := New(l)
+	p.ParseProgram()
+
+	expected := "1:8: expected } to close embedded expression, got IDENT instead"
+	if errors := p.Errors(); len(errors) == 0 || errors[0] != expected {
+		t.Errorf("wrong errors. expected %q first, got=%q", expected, errors)
+	}
+}
+
+func TestParsingArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+	if len(array.Elements) != 3 {
+		t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
+	}
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+	if end := array.End(); end.Offset != len(input) {
+		t.Errorf("array.End() wrong. got=%d", end.Offset)
+	}
+}
+
+func TestParsingEmptyArrayLiteral(t *testing.T) {
+	l := lexer.New("[]")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+	if len(array.Elements) != 0 {
+		t.Errorf("len(array.Elements) not 0. got=%d", len(array.Elements))
+	}
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+	input := "myArray[1 + 1]"
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+	if !testIdentifier(t, indexExp.Left, "myArray") {
+		return
+	}
+	if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
+		return
+	}
+	if pos, end := indexExp.Pos(), indexExp.End(); pos.Offset != 0 || end.Offset != len(input) {
+		t.Errorf("span wrong. got=%d-%d", pos.Offset, end.Offset)
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	input := `
+let x 5;
+let y = fn(a, 1) { a };
+let ok = 1;
+let f = fn() {
+	let = 2;
+	if (x { 3 }
+	return 4
+};
+ok + 1;
+return 5`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+
+	expected := []string{
+		"2:7: expected next token to be =, got INT instead",
+		"3:15: expected next token to be IDENT, got INT instead",
+		"6:6: expected next token to be IDENT, got = instead",
+		"7:8: expected next token to be ), got { instead",
+	}
+	errors := p.Errors()
+	if len(errors) != len(expected) {
+		t.Fatalf("wrong number of errors. got=%d (%q)", len(errors), errors)
+	}
+	for i, msg := range expected {
+		if errors[i] != msg {
+			t.Errorf("errors[%d] wrong. expected=%q, got=%q", i, msg, errors[i])
+		}
+	}
+
+	// the function literal itself parsed, so only its bad statements are
+	// lost, but the let around it is dropped as it contains errors
+	want := "let ok = 1;(ok + 1)return 5;"
+	if program.String() != want {
+		t.Errorf("program.String() wrong. expected=%q, got=%q", want, program.String())
+	}
+}
+
+func TestDeepNesting(t *testing.T) {
+	input := strings.Repeat("(", 100000) + "1" + strings.Repeat(")", 100000)
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 || !strings.HasSuffix(errors[0], "expression nested too deeply") {
+		t.Fatalf("wrong errors. got=%q", errors)
+	}
+	if len(program.Statements) != 0 {
+		t.Errorf("program has statements. got=%q", program.String())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	input := "let x = 1 @;\nlet 5 = x;\nlet y = (1 + 2;\nlet z = ;"
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	tests := []struct {
+		code     ErrorCode
+		pos      string
+		end      string
+		got      token.TokenType
+		expected []token.TokenType
+	}{
+		{ErrLexical, "1:11", "1:12", token.ILLEGAL, nil},
+		{ErrUnexpectedToken, "2:5", "2:6", token.INT, []token.TokenType{token.IDENT}},
+		{ErrUnexpectedToken, "3:15", "3:16", token.SEMICOLON, []token.TokenType{token.RPAREN}},
+		{ErrNoExpression, "4:9", "4:10", token.SEMICOLON, p.expressionStarts()},
+	}
+
+	errors := p.ParseErrors()
+	if len(errors) != len(tests) {
+		t.Fatalf("wrong number of errors. got=%d (%q)", len(errors), p.Errors())
+	}
+	for i, tt := range tests {
+		err := errors[i]
+		if err.Code != tt.code {
+			t.Errorf("errors[%d].Code wrong. expected=%q, got=%q", i, tt.code, err.Code)
+		}
+		if err.Pos.String() != tt.pos || err.End.String() != tt.end {
+			t.Errorf("errors[%d] span wrong. expected=%s-%s, got=%s-%s",
+				i, tt.pos, tt.end, err.Pos, err.End)
+		}
+		if err.Got.Type != tt.got {
+			t.Errorf("errors[%d].Got wrong. expected=%q, got=%q", i, tt.got, err.Got.Type)
+		}
+		if fmt.Sprint(err.Expected) != fmt.Sprint(tt.expected) {
+			t.Errorf("errors[%d].Expected wrong. expected=%v, got=%v", i, tt.expected, err.Expected)
+		}
+		if err.Error() != p.Errors()[i] {
+			t.Errorf("errors[%d] string view differs. %q != %q", i, err.Error(), p.Errors()[i])
+		}
+	}
+}
+
+func TestLoopStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while (x < 10) { f(x); }", "while(x < 10) f(x)"},
+		{"for (x in xs) { break; }", "for (x in xs) break;"},
+		{"for (k, v in {1: 2}) { continue }", "for (k, v in {1:2}) continue;"},
+		{"while (true) { let f = fn() { 1 }; for (c in s) { if (c) { break; } } }",
+			"whiletrue let f = fn() 1;for (c in s) ifc break;"},
+		{"while (c) { x = x + 1 };", "whilec (x = (x + 1))"},
+		{"for (i in [1]) { };", "for (i in [1]) "},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	forIn := New(lexer.New("for (i, x in [1]) { x }")).ParseProgram().Statements[0].(*ast.ForInStatement)
+	if !testIdentifier(t, forIn.Key, "i") || !testIdentifier(t, forIn.Value, "x") {
+		return
+	}
+	if _, ok := forIn.Iterable.(*ast.ArrayLiteral); !ok {
+		t.Errorf("forIn.Iterable is not *ast.ArrayLiteral. got=%T", forIn.Iterable)
+	}
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break;", "1:1: break outside loop"},
+		{"if (x) { continue; }", "1:10: continue outside loop"},
+		{"while (x) { fn() { break; } }", "1:20: break outside loop"},
+		{"for (x y) {}", "1:8: expected next token to be IN, got IDENT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) != 1 || errors[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 5;", "(x = 5)"},
+		{"x = y = z + 1;", "(x = (y = (z + 1)))"},
+		{"x += a * b", "(x += (a * b))"},
+		{"a[i + 1] -= 2", "((a[(i + 1)]) -= 2)"},
+		{"let f = fn() { count /= 2 };", "let f = fn() (count /= 2);"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestInvalidAssignmentTarget(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a + b = 1", "1:7: cannot assign to (a + b)"},
+		{"f() = 1", "1:5: cannot assign to f()"},
+		{"5 += 1", "1:3: cannot assign to 5"},
+		{`h["k"] *= 3 /= 4`, "1:13: cannot assign to 3"},
+		{"p.f() = 1", "1:7: cannot assign to p.f()"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+		errors := p.ParseErrors()
+		if len(errors) != 1 {
+			t.Fatalf("wrong number of errors for %q. got=%q", tt.input, p.Errors())
+		}
+		if errors[0].Error() != tt.expected || errors[0].Code != ErrInvalidAssignment {
+			t.Errorf("wrong error. expected=%q, got=%q (%s)", tt.expected, errors[0].Error(), errors[0].Code)
+		}
+	}
+}
+
+func TestHashLiteralSourceOrder(t *testing.T) {
+	input := `{"z": 1, "a": 2, 10: 3, true: 4, "m": 5}`
+	expected := "{z:1,a:2,10:3,true:4,m:5}"
+	for i := 0; i < 20; i++ {
+		program := New(lexer.New(input)).ParseProgram()
+		if program.String() != expected {
+			t.Fatalf("expected=%q, got=%q", expected, program.String())
+		}
+	}
+}
+
+func TestMatchExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match (x) { 1 => "one", _ => "many" }`, `match (x) { 1 => one, _ => many }`},
+		{`match (x) { -1 => a, 2.5 => b, "s" => c, true => d, }`, `match (x) { (-1) => a, 2.5 => b, "s" => c, true => d }`},
+		{`match (f(x)) { n if n > 0 => n * 2, n => -n }`, `match (f(x)) { n if (n > 0) => (n * 2), n => (-n) }`},
+		{`match (xs) { [] => 0, [a, [b, _]] => a, [a, ...rest] => rest }`, `match (xs) { [] => 0, [a, [b, _]] => a, [a, ...rest] => rest }`},
+		{`match (h) { {name, "age": years, 1: [x]} => name, {} => 0 }`, `match (h) { {name: name, age: years, 1: [x]} => name, {} => 0 }`},
+		{`match (x) { }`, `match (x) {  }`},
+		{`let y = match (x) { _ => 1 } + 1;`, `let y = (match (x) { _ => 1 } + 1);`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestMatchExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		code     ErrorCode
+	}{
+		{`match (x) { a + 1 => 2 }`, "1:15: expected next token to be =>, got + instead", ErrUnexpectedToken},
+		{`match (x) { fn => 1 }`, "1:13: expected a pattern, got FUNCTION instead", ErrInvalidPattern},
+		{`match (x) { -a => 1 }`, "1:14: expected a number after - in pattern, got IDENT instead", ErrInvalidPattern},
+		{`match (x) { [...r, a] => 1 }`, "1:18: expected next token to be ], got , instead", ErrUnexpectedToken},
+		{`match (x) { [...1] => 1 }`, "1:17: expected next token to be IDENT, got INT instead", ErrUnexpectedToken},
+		{`match (x) { {[a]: 1} => 1 }`, "1:14: expected a hash pattern key, got [ instead", ErrInvalidPattern},
+		{`match (x) { 1 => 2 3 => 4 }`, "1:20: expected next token to be ,, got INT instead", ErrUnexpectedToken},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+		errors := p.ParseErrors()
+		if len(errors) != 1 {
+			t.Fatalf("wrong number of errors for %q. got=%q", tt.input, p.Errors())
+		}
+		if errors[0].Error() != tt.expected || errors[0].Code != tt.code {
+			t.Errorf("wrong error. expected=%q, got=%q (%s)", tt.expected, errors[0].Error(), errors[0].Code)
+		}
+	}
+}
+
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b, ...rest] = xs;", "let [a, b, ...rest] = xs;"},
+		{"let [] = xs", "let [] = xs;"},
+		{"let [_, [x, y]] = f(1);", "let [_, [x, y]] = f(1);"},
+		{"let {name, age: years} = person;", "let {name: name, age: years} = person;"},
+		{`let {"k": [first, ..._], 2: two} = h;`, "let {k: [first, ..._], 2: two} = h;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("stmt is not *ast.LetStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Pattern == nil || stmt.Name != nil {
+			t.Errorf("stmt has Name %v and Pattern %v", stmt.Name, stmt.Pattern)
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := lexer.New("let [a = [1]")
+	p := New(l)
+	p.ParseProgram()
+	expected := "1:8: expected next token to be ,, got = instead"
+	if errors := p.Errors(); len(errors) != 1 || errors[0] != expected {
+		t.Errorf("wrong errors. expected=%q, got=%q", expected, errors)
+	}
+}
+
+func TestDefaultAndRestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		defaults int
+		rest     string
+	}{
+		{"fn(x, y = 10) { x }", "fn(x, y = 10) x", 1, ""},
+		{"fn(x = 1, y = x * 2) { y }", "fn(x = 1, y = (x * 2)) y", 2, ""},
+		{"fn(first, ...rest) { rest }", "fn(first, ...rest) rest", 0, "rest"},
+		{"fn(...args) { args }", "fn(...args) args", 0, "args"},
+		{"fn(a, b = [1], ...c) { c }", "fn(a, b = [1], ...c) c", 1, "c"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+		function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+		if len(function.Defaults) != len(function.Parameters) {
+			t.Errorf("Defaults not parallel to Parameters. got=%d, want=%d", len(function.Defaults), len(function.Parameters))
+		}
+		defaults := 0
+		for _, def := range function.Defaults {
+			if def != nil {
+				defaults++
+			}
+		}
+		if defaults != tt.defaults {
+			t.Errorf("wrong number of defaults. got=%d, want=%d", defaults, tt.defaults)
+		}
+		if tt.rest == "" && function.Rest != nil || tt.rest != "" && (function.Rest == nil || function.Rest.Value != tt.rest) {
+			t.Errorf("wrong rest parameter. got=%v, want=%q", function.Rest, tt.rest)
+		}
+	}
+}
+
+func TestNamedArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f(y: 2, x: 1)", "f(y: 2, x: 1)"},
+		{"f(1, z: a + b)", "f(1, z: (a + b))"},
+		{"f(g(x: 1))", "f(g(x: 1))"},
+		{`f({a: 1})`, "f({a:1})"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+		code     ErrorCode
+	}{
+		{"f(x: 1, 2)", "1:9: positional argument after named argument", ErrInvalidArgument},
+		{"fn(x, ...r, y) { x }", "1:11: expected next token to be ), got , instead", ErrUnexpectedToken},
+		{"fn(...) { 1 }", "1:7: expected next token to be IDENT, got ) instead", ErrUnexpectedToken},
+		{"macro(x = 1) { x }", "1:9: expected next token to be ), got = instead", ErrUnexpectedToken},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+		errors := p.ParseErrors()
+		if len(errors) != 1 {
+			t.Fatalf("wrong number of errors for %q. got=%q", tt.input, p.Errors())
+		}
+		if errors[0].Error() != tt.expected || errors[0].Code != tt.code {
+			t.Errorf("wrong error. expected=%q, got=%q (%s)", tt.expected, errors[0].Error(), errors[0].Code)
+		}
+	}
+}
+
+func TestPipeAndMethodCallParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x |> f", "(x |> f)"},
+		{"x |> f(1) |> g", "((x |> f(1)) |> g)"},
+		{"a + b |> f", "((a + b) |> f)"},
+		{"a || b |> f", "((a || b) |> f)"},
+		{"y = x |> f", "(y = (x |> f))"},
+		{"x.f()", "x.f()"},
+		{"x.f(1, n: 2).g()", "x.f(1, n: 2).g()"},
+		{"-x.f()", "(-x.f())"},
+		{"a + b.f() * 2", "(a + (b.f() * 2))"},
+		{"xs[0].f()[1]", "((xs[0]).f()[1])"},
+		{"f(x).g()", "f(x).g()"},
+		{"x |> y.f(1)", "(x |> y.f(1))"},
+		{"x.f", "x.f"},
+		{"m.f.g()", "m.f.g()"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := lexer.New("x.")
+	p := New(l)
+	p.ParseProgram()
+	expected := "1:3: expected next token to be IDENT, got EOF instead"
+	if errors := p.Errors(); len(errors) != 1 || errors[0] != expected {
+		t.Errorf("wrong errors. expected=%q, got=%q", expected, errors)
+	}
+}
+
+func TestImportAndExportStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "lib/math.osp" as math;`, `import "lib/math.osp" as math;`},
+		{`import "a.osp" as a import "b.osp" as b`, `import "a.osp" as a;import "b.osp" as b;`},
+		{`export let x = 1;`, `export let x = 1;`},
+		{`export let [a, b] = xs;`, `export let [a, b] = xs;`},
+		{`math.pi`, `math.pi`},
+		{`math.square(2)`, `math.square(2)`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestImportAndExportErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		code     ErrorCode
+	}{
+		{`import math`, "1:8: expected next token to be STRING, got IDENT instead", ErrUnexpectedToken},
+		{`import "math.osp"`, "1:18: expected next token to be AS, got EOF instead", ErrUnexpectedToken},
+		{`export x = 1`, "1:8: expected next token to be LET, got IDENT instead", ErrUnexpectedToken},
+		{`let f = fn() { export let x = 1; }`, "1:16: export outside the top level", ErrMisplacedExport},
+		{`if (true) { export let x = 1 }`, "1:13: export outside the top level", ErrMisplacedExport},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+		errors := p.ParseErrors()
+		if len(errors) != 1 {
+			t.Fatalf("wrong number of errors for %q. got=%q", tt.input, p.Errors())
+		}
+		if errors[0].Error() != tt.expected || errors[0].Code != tt.code {
+			t.Errorf("wrong error. expected=%q, got=%q (%s)", tt.expected, errors[0].Error(), errors[0].Code)
+		}
+	}
+}
+
+func TestTryExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { f() } catch (e) { g(e) }", "try f() catch (e) g(e)"},
+		{"try { f() } catch { 0 }", "try f() catch 0"},
+		{"try { f() } finally { g() }", "try f() finally g()"},
+		{"let x = try { f() } catch (e) { 1 } finally { g() };", "let x = try f() catch (e) 1 finally g();"},
+		{"throw error(\"bad\");", "throw error(bad);"},
+		{"throw e", "throw e;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestTryExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { f() }", "1:12: expected catch or finally after try block, got EOF instead"},
+		{"try { f() } catch (1) { }", "1:20: expected next token to be IDENT, got INT instead"},
+		{"try { f() } catch (e { }", "1:22: expected next token to be ), got { instead"},
+		{"try f() catch { }", "1:5: expected next token to be {, got IDENT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+		errors := p.ParseErrors()
+		if len(errors) != 1 {
+			t.Fatalf("wrong number of errors for %q. got=%q", tt.input, p.Errors())
+		}
+		if errors[0].Error() != tt.expected || errors[0].Code != ErrUnexpectedToken {
+			t.Errorf("wrong error. expected=%q, got=%q (%s)", tt.expected, errors[0].Error(), errors[0].Code)
+		}
+	}
+}
+
+func TestStructStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct Point { x, y }", "struct Point { x, y }"},
+		{"struct Empty {}", "struct Empty {}"},
+		{"struct P { x; y, }", "struct P { x, y }"},
+		{"struct P { x, fn norm() { self.x * self.x } }", "struct P { x, fn norm() (self.x * self.x) }"},
+		{"struct P { fn at(i, d = 0, ...r) { i } x }", "struct P { x, fn at(i, d = 0, ...r) i }"},
+		{"p.x = 1; p.x += 2", "(p.x = 1)(p.x += 2)"},
+		{"struct P { x }; P(1)", "struct P { x }P(1)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	program := testParseStruct(t, "struct Point { x, fn norm() { 1 } }")
+	if program.Name.Value != "Point" || len(program.Fields) != 1 || len(program.Methods) != 1 {
+		t.Fatalf("wrong struct. got=%+v", program)
+	}
+	if program.Methods[0].Name.Value != "norm" || len(program.Methods[0].Function.Parameters) != 0 {
+		t.Errorf("wrong method. got=%q", program.Methods[0].String())
+	}
+}
+
+func testParseStruct(t *testing.T, input string) *ast.StructStatement {
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt, ok := program.Statements[0].(*ast.StructStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.StructStatement. got=%T", program.Statements[0])
+	}
+	return stmt
+}
+
+func TestStructErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		code     ErrorCode
+	}{
+		{"struct { x }", "1:8: expected next token to be IDENT, got { instead", ErrUnexpectedToken},
+		{"struct P { 1 }", "1:12: expected a field or method, got INT instead", ErrUnexpectedToken},
+		{"struct P { x", "1:13: expected a field or method, got EOF instead", ErrUnexpectedToken},
+		{"struct P { fn () { 1 } }", "1:15: expected next token to be IDENT, got ( instead", ErrUnexpectedToken},
+		{"struct P { x, y, x }", "1:18: duplicate member x in struct P", ErrDuplicateMember},
+		{"struct P { x, fn x() { 1 } }", "1:18: duplicate member x in struct P", ErrDuplicateMember},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+		errors := p.ParseErrors()
+		if len(errors) != 1 {
+			t.Fatalf("wrong number of errors for %q. got=%q", tt.input, p.Errors())
+		}
+		if errors[0].Error() != tt.expected || errors[0].Code != tt.code {
+			t.Errorf("wrong error. expected=%q, got=%q (%s)", tt.expected, errors[0].Error(), errors[0].Code)
+		}
 	}
 }
@@ -0,0 +1,186 @@
+package parser
+
+import (
+	"OSPLang/ast"
+	"OSPLang/token"
+)
+
+//patternStarts holds the tokens that can begin a pattern, sorted
+var patternStarts = []token.TokenType{
+	token.MINUS,
+	token.LBRACKET,
+	token.LBRACE,
+	token.FALSE,
+	token.FLOAT,
+	token.IDENT,
+	token.INT,
+	token.STRING,
+	token.TRUE,
+}
+
+//parseMatchExpression parses match (subject) { pattern => body, ... }
+//where an arm may also carry a guard: pattern if condition => body
+func (p *Parser) parseMatchExpression() ast.Expression {
+	exp := &ast.MatchExpression{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	exp.Subject = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		arm := &ast.MatchArm{Pattern: p.parsePattern()}
+		if arm.Pattern == nil {
+			return nil
+		}
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			p.nextToken()
+			arm.Guard = p.parseExpression(LOWEST)
+		}
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+		p.nextToken()
+		arm.Body = p.parseExpression(LOWEST)
+		exp.Arms = append(exp.Arms, arm)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+	exp.Rbrace = p.curToken.Pos
+	return exp
+}
+
+//parsePattern parses the pattern that starts at the current token, which
+//is left on its last token
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		if p.curToken.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.curToken}
+		}
+		return &ast.BindingPattern{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE:
+		value := p.prefixParseFns[p.curToken.Type]()
+		if value == nil {
+			return nil
+		}
+		return &ast.LiteralPattern{Value: value}
+	case token.MINUS:
+		minus := p.curToken
+		if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.FLOAT) {
+			p.addError(ErrInvalidPattern, p.peekToken, []token.TokenType{token.FLOAT, token.INT},
+				"expected a number after - in pattern, got %s instead", p.peekToken.Type)
+			return nil
+		}
+		p.nextToken()
+		value := p.prefixParseFns[p.curToken.Type]()
+		if value == nil {
+			return nil
+		}
+		return &ast.LiteralPattern{Value: &ast.PrefixExpression{Token: minus, Operator: "-", Right: value}}
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	case token.ILLEGAL:
+		p.noPrefixParseFnError(token.ILLEGAL)
+		return nil
+	}
+	p.addError(ErrInvalidPattern, p.curToken, patternStarts,
+		"expected a pattern, got %s instead", p.curToken.Type)
+	return nil
+}
+
+//parseArrayPattern parses [p1, p2, ...rest], where the rest is optional
+//and must come last
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+	pattern.Elements = []ast.Pattern{}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = p.parsePattern()
+			if !p.expectPeek(token.RBRACKET) {
+				return nil
+			}
+			pattern.Rbracket = p.curToken.Pos
+			return pattern
+		}
+
+		el := p.parsePattern()
+		if el == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, el)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+	pattern.Rbracket = p.curToken.Pos
+	return pattern
+}
+
+//parseHashPattern parses {key: pattern, name, ...}. A bare name is short
+//for name: name.
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curToken}
+	pattern.Fields = []*ast.HashPatternField{}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		field := &ast.HashPatternField{}
+		switch p.curToken.Type {
+		case token.IDENT:
+			field.Key = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.peekTokenIs(token.COLON) {
+				field.Value = p.parsePattern()
+			}
+		case token.STRING, token.INT, token.TRUE, token.FALSE:
+			field.Key = p.prefixParseFns[p.curToken.Type]()
+			if field.Key == nil {
+				return nil
+			}
+		default:
+			p.addError(ErrInvalidPattern, p.curToken,
+				[]token.TokenType{token.FALSE, token.IDENT, token.INT, token.STRING, token.TRUE},
+				"expected a hash pattern key, got %s instead", p.curToken.Type)
+			return nil
+		}
+
+		if field.Value == nil {
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			p.nextToken()
+			field.Value = p.parsePattern()
+			if field.Value == nil {
+				return nil
+			}
+		}
+		pattern.Fields = append(pattern.Fields, field)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+	pattern.Rbrace = p.curToken.Pos
+	return pattern
+}
@@ -5,27 +5,44 @@ import (
 	"OSPLang/lexer"
 	"OSPLang/object"
 	"OSPLang/parser"
+	"OSPLang/token"
 	"bufio"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 const PROMPT = "#>"
 
+//CONTINUE_PROMPT is shown while a statement spans several lines
+const CONTINUE_PROMPT = ".."
+
+//SearchPaths returns the directories that imports are looked for in,
+//after the importing file's own: those listed in the OSPPATH environment
+//variable, then the current directory
+func SearchPaths() []string {
+	return append(filepath.SplitList(os.Getenv("OSPPATH")), ".")
+}
+
 //Start ...
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
 	env := object.NewEnvironment()
+	env.SetImporter(evaluator.NewLoader(SearchPaths()...))
+	// macros see what earlier chunks defined, so that the names made up
+	// when they expand stay clear of it
+	macroEnv := object.NewEnclosedEnvironment(env)
 
 	for {
-		fmt.Printf(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		fmt.Fprint(out, PROMPT)
+		chunk, ok := readChunk(scanner, out)
+		if !ok {
 			return
 		}
 
-		line := scanner.Text()
-		l := lexer.New(line)
+		l := lexer.New(chunk)
 		p := parser.New(l)
 
 		program := p.ParseProgram()
@@ -34,7 +51,14 @@ func Start(in io.Reader, out io.Writer) {
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
+		evaluator.DefineMacros(program, macroEnv)
+		expanded, err := evaluator.ExpandMacros(program, macroEnv)
+		if err != nil {
+			fmt.Fprintf(out, "macro expansion failed: %v\n", err)
+			continue
+		}
+
+		evaluated := evaluator.Eval(expanded, env)
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
@@ -43,6 +67,85 @@ func Start(in io.Reader, out io.Writer) {
 
 }
 
+//Run streams a whole script from in through the lexer and parser and
+//evaluates it, writing errors to out. It reports whether the script ran
+//without parser or runtime errors.
+func Run(filename string, in io.Reader, out io.Writer) bool {
+	l := lexer.NewFile(filename, in)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if err := l.Err(); err != nil {
+		fmt.Fprintf(out, "could not read %s: %v\n", filename, err)
+		return false
+	}
+	if len(p.Errors()) != 0 {
+		printParserErrors(out, p.Errors())
+		return false
+	}
+
+	macroEnv := object.NewEnvironment()
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, err := evaluator.ExpandMacros(program, macroEnv)
+	if err != nil {
+		fmt.Fprintf(out, "macro expansion failed: %v\n", err)
+		return false
+	}
+
+	loader := evaluator.NewLoader(SearchPaths()...)
+	loader.Main(filename)
+	env := object.NewEnvironment()
+	env.SetImporter(loader)
+
+	evaluated := evaluator.Eval(expanded, env)
+	if evaluated != nil && evaluated.Type() == object.ERROR_OBJ {
+		io.WriteString(out, evaluated.Inspect())
+		io.WriteString(out, "\n")
+		return false
+	}
+	return true
+}
+
+//readChunk reads lines until they form complete input: every bracket is
+//closed and no string or block comment is left open. A blank line ends
+//the chunk early so the parser can report what is wrong with it.
+func readChunk(scanner *bufio.Scanner, out io.Writer) (string, bool) {
+	var chunk strings.Builder
+	for {
+		if !scanner.Scan() {
+			return chunk.String(), chunk.Len() > 0
+		}
+		line := scanner.Text()
+		chunk.WriteString(line)
+		chunk.WriteString("\n")
+		if strings.TrimSpace(line) == "" || !incomplete(chunk.String()) {
+			return chunk.String(), true
+		}
+		fmt.Fprint(out, CONTINUE_PROMPT)
+	}
+}
+
+//incomplete reports whether src stops in the middle of a bracketed
+//construct, a string or a block comment
+func incomplete(src string) bool {
+	l := lexer.New(src)
+	depth := 0
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LPAREN, token.LBRACE, token.LBRACKET, token.INTERP_HEAD:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACKET, token.INTERP_TAIL:
+			depth--
+		}
+	}
+	for _, d := range l.Diagnostics() {
+		if strings.HasPrefix(d.Reason, "unterminated") {
+			return true
+		}
+	}
+	return depth > 0
+}
+
 func printParserErrors(out io.Writer, errors []string) {
 	io.WriteString(out, "Woops! We ran into some pronblem here!\n")
 	io.WriteString(out, " parser errors:\n")
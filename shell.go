@@ -8,6 +8,10 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runFile(os.Args[1]))
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -16,3 +20,18 @@ func main() {
 	repl.Start(os.Stdin, os.Stdout)
 
 }
+
+//runFile executes the script at path and returns the process exit code
+func runFile(path string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+
+	if !repl.Run(path, f, os.Stderr) {
+		return 1
+	}
+	return 0
+}
@@ -1,10 +1,58 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
+//Position is a location in the source: the file name, the byte offset
+//and the 1-based line and column.
+type Position struct {
+	Filename string
+	Offset   int
+	Line     int
+	Column   int
+}
+
+//IsValid reports whether the position was recorded by a lexer
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+//String returns the position as file:line:column, line:column or "-"
+func (p Position) String() string {
+	s := p.Filename
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // position of the first character
+	End     Position // position just past the last character
+	Leading []Trivia // whitespace and comments before the token, if kept
+}
+
+//TriviaKind is the kind of source text the lexer skips between tokens
+type TriviaKind string
+
+const (
+	WHITESPACE    TriviaKind = "WHITESPACE"
+	LINE_COMMENT  TriviaKind = "LINE_COMMENT"
+	BLOCK_COMMENT TriviaKind = "BLOCK_COMMENT"
+)
+
+//Trivia is a run of whitespace or a comment, kept verbatim
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+	Pos  Position
 }
 
 const (
@@ -14,7 +62,13 @@ const (
 	IDENT = "IDENT" // add, foobar, x, y, ...
 	INT   = "INT"
 	// 1343456
+	FLOAT = "FLOAT"
+	// 3.14, 1e9
 	STRING = "STRING"
+	// "a ${ , } b ${ and } c" around the expressions of "a ${x} b ${y} c"
+	INTERP_HEAD = "INTERP_HEAD"
+	INTERP_MID  = "INTERP_MID"
+	INTERP_TAIL = "INTERP_TAIL"
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
@@ -25,6 +79,9 @@ const (
 	COLON     = ":"
 	LBRACKET  = "["
 	RBRACKET  = "]"
+	ARROW     = "=>"
+	ELLIPSIS  = "..."
+	DOT       = "."
 	// Keywords
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
@@ -33,6 +90,21 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	IN       = "IN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	MACRO    = "MACRO"
+	MATCH    = "MATCH"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
+	AS       = "AS"
+	THROW    = "THROW"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	STRUCT   = "STRUCT"
 	// Operators
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -44,16 +116,41 @@ const (
 	GT       = ">"
 	EQ       = "=="
 	NOT_EQ   = "!="
+	LT_EQ    = "<="
+	GT_EQ    = ">="
+	AND      = "&&"
+	OR       = "||"
+	PIPE     = "|>"
+	// Assignment operators besides =
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
 )
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"macro":    MACRO,
+	"match":    MATCH,
+	"import":   IMPORT,
+	"export":   EXPORT,
+	"as":       AS,
+	"throw":    THROW,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"struct":   STRUCT,
 }
 
 //LookupIdent ...